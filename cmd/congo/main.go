@@ -14,15 +14,31 @@ import (
 )
 
 var (
-	cpuProfile  = flag.String("cpuprofile", "", "write cpu profile to file")
-	minCoverage = flag.Float64("coverage", 0.0, "minimum coverage")
-	maxExec     = flag.Uint("maxexec", 0, "maximum execution time")
-	o           = flag.String("o", "", "destination path for generated test code")
-	ssa         = flag.Bool("ssa", false, "dump SSA")
-	ast         = flag.Bool("ast", false, "dump AST")
-	logLevel    = flag.String("log", "info", "log level (debug, info, error, disabled)")
-	funcName    = flag.String("f", "", "name of the target function")
-	runner      = flag.String("r", "", "test template")
+	cpuProfile   = flag.String("cpuprofile", "", "write cpu profile to file")
+	minCoverage  = flag.Float64("coverage", 0.0, "minimum coverage")
+	maxExec      = flag.Uint("maxexec", 0, "maximum execution time")
+	queryTimeout = flag.Duration("querytimeout", 0, "time limit of each solver query")
+	solverBudget = flag.Duration("solverbudget", 0, "total time limit of solver queries")
+	timeout      = flag.Duration("timeout", 0, "time limit of concolic execution for each function")
+	maxSteps     = flag.Uint("maxsteps", 0, "maximum number of instructions executed in each run")
+	maxTrace     = flag.Uint("maxtrace", 0, "maximum number of instructions traced in each run")
+	loopBound    = flag.Uint("loopbound", 0, "maximum iteration of a loop in which branches are negated")
+	summarize    = flag.Bool("summarizeloops", false, "summarize simple counting loops by solving their trip counts")
+	metric       = flag.String("metric", "", "coverage metric (block, edge)")
+	solverName   = flag.String("solver", "", "solver backend (z3, smtlib)")
+	solverCmd    = flag.String("solvercmd", "", "command line of the solver process for the smtlib backend (default \"z3 -in -smt2\")")
+	dumpSMT      = flag.String("dump-smt", "", "directory to which each solver query is written as an SMT-LIB2 file")
+	readable     = flag.Bool("readable", false, "prefer small integers, short strings, and printable characters as inputs")
+	overflow     = flag.Bool("overflow", false, "report the inputs that make integer operations and conversions overflow")
+	overflowTest = flag.Bool("overflow-tests", false, "add the runs with the inputs that make integer operations overflow to the test")
+	strategy     = flag.String("strategy", "", "path-exploration strategy (dfs, bfs, generational, random, directed)")
+	o            = flag.String("o", "", "destination path for generated test code")
+	ssa          = flag.Bool("ssa", false, "dump SSA")
+	ast          = flag.Bool("ast", false, "dump AST")
+	logLevel     = flag.String("log", "info", "log level (debug, info, error, disabled)")
+	funcName     = flag.String("f", "", "name of the target function (T.M for a method M of type T)")
+	runner       = flag.String("r", "", "test template")
+	inPackage    = flag.Bool("inpkg", false, "generate tests in the target package to test unexported functions")
 )
 
 func main() {
@@ -51,9 +67,25 @@ func main() {
 	}
 	config := &congo.Config{
 		FuncNames: funcNames,
+		InPackage: *inPackage,
 		ExecuteOption: congo.ExecuteOption{
-			MaxExec:     *maxExec,
-			MinCoverage: *minCoverage,
+			MaxExec:        *maxExec,
+			MinCoverage:    *minCoverage,
+			Strategy:       *strategy,
+			QueryTimeout:   *queryTimeout,
+			SolverBudget:   *solverBudget,
+			Timeout:        *timeout,
+			MaxSteps:       *maxSteps,
+			MaxTrace:       *maxTrace,
+			LoopBound:      *loopBound,
+			SummarizeLoops: *summarize,
+			Metric:         *metric,
+			Solver:         *solverName,
+			SolverCommand:  *solverCmd,
+			DumpSMT:        *dumpSMT,
+			Readable:       *readable,
+			Overflow:       *overflow,
+			OverflowTests:  *overflowTest,
 		},
 	}
 	c, err := congo.Load(config, targetPackagePath)
@@ -82,6 +114,10 @@ func main() {
 		if err != nil {
 			log.Error.Fatalf("failed to perform concolic execution: %+v", err)
 		}
+		log.Info.Printf("%s: block coverage %.3f, edge coverage %.3f", name, result.BlockCoverage, result.EdgeCoverage)
+		for _, of := range result.Overflows {
+			log.Info.Printf("%s: %s overflows at %s with %v", name, of.Instr, of.Pos, of.Values)
+		}
 		f, err := result.GenerateTest()
 		if err != nil {
 			log.Error.Fatalf("failed to generate test: %+v", err)
@@ -2,11 +2,13 @@ package congo
 
 import (
 	"bytes"
+	"context"
 	"go/ast"
 	"go/format"
 	"go/token"
 	"go/types"
 	"io"
+	"time"
 
 	"github.com/ajalab/congo/interp"
 	"github.com/ajalab/congo/log"
@@ -19,6 +21,8 @@ import (
 
 // Program is a type that contains information of the target program.
 type Program struct {
+	inPackage          bool
+	concreteTypes      []types.Type
 	runnerFile         *ast.File
 	runnerTypesInfo    *types.Info
 	runnerPackage      *ssa.Package
@@ -30,11 +34,58 @@ type Program struct {
 type ExecuteOption struct {
 	MaxExec     uint    `key:"maxexec"`
 	MinCoverage float64 `key:"cover"`
+	// Strategy is the name of the path-exploration strategy (see NewStrategy).
+	Strategy string `key:"strategy"`
+	// QueryTimeout is the time limit of each query to the solver.
+	QueryTimeout time.Duration `key:"querytimeout"`
+	// SolverBudget is the total time limit of the queries. No limit is imposed if it is zero.
+	SolverBudget time.Duration `key:"solverbudget"`
+	// Timeout is the time limit of the whole concolic execution. No limit is imposed if it is zero.
+	Timeout time.Duration `key:"timeout"`
+	// MaxSteps is the maximum number of instructions the interpreter executes in each run.
+	MaxSteps uint `key:"maxsteps"`
+	// MaxTrace is the maximum number of instructions recorded in the trace of each run.
+	MaxTrace uint `key:"maxtrace"`
+	// LoopBound is the maximum iteration of a loop in which the branches are negated.
+	// No bound is imposed if it is zero.
+	LoopBound uint `key:"loopbound"`
+	// SummarizeLoops summarizes simple counting loops (see solver.CountingLoop):
+	// their conditions after the first iteration are replaced with a branch at the exit of the loops,
+	// which solves their trip counts from the bounds, and whose negations yield other trip counts.
+	SummarizeLoops bool `key:"summarizeloops"`
+	// Metric is the coverage metric used for MinCoverage, the Coverage of the result, and the prioritization of branches
+	// by the dfs, generational, and directed strategies.
+	// It is either "block" (basic blocks of the target function) or "edge"
+	// (both sides of each branch of the target function).
+	Metric string `key:"metric"`
+	// Solver is the name of the solver backend (see solver.Backends).
+	Solver string `key:"solver"`
+	// SolverCommand is the command line of the solver process for the smtlib backend
+	// (e.g., "cvc5 --lang smt2 --incremental"). The default one is used if it is empty.
+	SolverCommand string `key:"solvercmd"`
+	// DumpSMT is the directory to which each query is written as an SMT-LIB2 file.
+	// No query is written if it is empty.
+	DumpSMT string `key:"dumpsmt"`
+	// Readable makes the solver prefer small absolute values of integers, short strings,
+	// and printable characters, which makes the generated tests easier to read.
+	Readable bool `key:"readable"`
+	// Overflow makes Congo check whether the integer operations and conversions in each run
+	// can overflow along the path, and report the inputs that make them overflow (see ExecuteResult.Overflows).
+	Overflow bool `key:"overflow"`
+	// OverflowTests adds the runs with the inputs reported by Overflow to the generated test.
+	// The runs are counted toward MaxExec and the coverage. It implies Overflow.
+	OverflowTests bool `key:"overflowtests"`
 }
 
 var defaultExecuteOption = &ExecuteOption{
-	MaxExec:     10,
-	MinCoverage: 1.0,
+	MaxExec:      10,
+	MinCoverage:  1.0,
+	Strategy:     "dfs",
+	QueryTimeout: 10 * time.Second,
+	MaxSteps:     10000000,
+	MaxTrace:     1000000,
+	Metric:       "block",
+	Solver:       solver.DefaultBackend(),
 }
 
 // Fill fills the fields in ExecuteOption with those in src.
@@ -50,6 +101,51 @@ func (eo *ExecuteOption) Fill(src *ExecuteOption, overwrite bool) *ExecuteOption
 		if src.MinCoverage != 0 {
 			eo.MinCoverage = src.MinCoverage
 		}
+		if src.Strategy != "" {
+			eo.Strategy = src.Strategy
+		}
+		if src.QueryTimeout != 0 {
+			eo.QueryTimeout = src.QueryTimeout
+		}
+		if src.SolverBudget != 0 {
+			eo.SolverBudget = src.SolverBudget
+		}
+		if src.Timeout != 0 {
+			eo.Timeout = src.Timeout
+		}
+		if src.MaxSteps != 0 {
+			eo.MaxSteps = src.MaxSteps
+		}
+		if src.MaxTrace != 0 {
+			eo.MaxTrace = src.MaxTrace
+		}
+		if src.LoopBound != 0 {
+			eo.LoopBound = src.LoopBound
+		}
+		if src.SummarizeLoops {
+			eo.SummarizeLoops = src.SummarizeLoops
+		}
+		if src.Metric != "" {
+			eo.Metric = src.Metric
+		}
+		if src.Solver != "" {
+			eo.Solver = src.Solver
+		}
+		if src.SolverCommand != "" {
+			eo.SolverCommand = src.SolverCommand
+		}
+		if src.DumpSMT != "" {
+			eo.DumpSMT = src.DumpSMT
+		}
+		if src.Readable {
+			eo.Readable = src.Readable
+		}
+		if src.Overflow {
+			eo.Overflow = src.Overflow
+		}
+		if src.OverflowTests {
+			eo.OverflowTests = src.OverflowTests
+		}
 	} else {
 		if eo.MaxExec == 0 {
 			eo.MaxExec = src.MaxExec
@@ -57,6 +153,51 @@ func (eo *ExecuteOption) Fill(src *ExecuteOption, overwrite bool) *ExecuteOption
 		if eo.MinCoverage == 0.0 {
 			eo.MinCoverage = src.MinCoverage
 		}
+		if eo.Strategy == "" {
+			eo.Strategy = src.Strategy
+		}
+		if eo.QueryTimeout == 0 {
+			eo.QueryTimeout = src.QueryTimeout
+		}
+		if eo.SolverBudget == 0 {
+			eo.SolverBudget = src.SolverBudget
+		}
+		if eo.Timeout == 0 {
+			eo.Timeout = src.Timeout
+		}
+		if eo.MaxSteps == 0 {
+			eo.MaxSteps = src.MaxSteps
+		}
+		if eo.MaxTrace == 0 {
+			eo.MaxTrace = src.MaxTrace
+		}
+		if eo.LoopBound == 0 {
+			eo.LoopBound = src.LoopBound
+		}
+		if !eo.SummarizeLoops {
+			eo.SummarizeLoops = src.SummarizeLoops
+		}
+		if eo.Metric == "" {
+			eo.Metric = src.Metric
+		}
+		if eo.Solver == "" {
+			eo.Solver = src.Solver
+		}
+		if eo.SolverCommand == "" {
+			eo.SolverCommand = src.SolverCommand
+		}
+		if eo.DumpSMT == "" {
+			eo.DumpSMT = src.DumpSMT
+		}
+		if !eo.Readable {
+			eo.Readable = src.Readable
+		}
+		if !eo.Overflow {
+			eo.Overflow = src.Overflow
+		}
+		if !eo.OverflowTests {
+			eo.OverflowTests = src.OverflowTests
+		}
 	}
 	return eo
 }
@@ -79,23 +220,131 @@ type Congo struct {
 }
 
 // Execute executes concolic execution.
-// The iteration time is bounded by maxExec and stopped when minCoverage is accomplished.
+// It is equivalent to ExecuteContext with the background context.
 func (c *Congo) Execute(funcName string) (*ExecuteResult, error) {
+	return c.ExecuteContext(context.Background(), funcName)
+}
+
+// ExecuteContext executes concolic execution.
+// The iteration time is bounded by maxExec and stopped when minCoverage is accomplished.
+// The branch to be negated in each iteration is selected by the strategy given in the execute option.
+// The execution is also stopped when ctx is done or the timeout given in the execute option expires,
+// in which case the result contains the runs and the coverage achieved so far.
+func (c *Congo) ExecuteContext(ctx context.Context, funcName string) (*ExecuteResult, error) {
 	target, ok := c.targets[funcName]
 	if !ok {
 		return nil, errors.Errorf("function %s does not exist", funcName)
 	}
+	if err := checkMetric(target.Metric); err != nil {
+		return nil, err
+	}
+	strategy, err := NewStrategy(target.Strategy, target.f, target.Metric)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := solver.NewBackend(target.Solver, target.SolverCommand)
+	if err != nil {
+		return nil, err
+	}
+	backend.SetReadable(target.Readable)
+	backend.SetOverflow(target.Overflow || target.OverflowTests)
+	var dumper *queryDumper
+	if target.DumpSMT != "" {
+		if dumper, err = newQueryDumper(target.DumpSMT, funcName); err != nil {
+			return nil, err
+		}
+	}
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
 	n := len(target.symbols)
 	solutions := make([]solver.Solution, n)
-	covered := make(map[*ssa.BasicBlock]struct{})
+	cov := newCoverage(target.f)
 	coverage := 0.0
 	var runResults []*RunResult
+	var unknowns []solver.Branch
+	var overflows []Overflow
+	// overflowed is the set of the instructions found to overflow.
+	overflowed := make(map[ssa.Instruction]bool)
+	// solverTime is the total time spent on the queries.
+	var solverTime time.Duration
+	// queryTimeout returns the time limit of the next query.
+	// It reports false if the solver budget has been exhausted.
+	queryTimeout := func() (time.Duration, bool) {
+		timeout := target.QueryTimeout
+		if target.SolverBudget > 0 {
+			remaining := target.SolverBudget - solverTime
+			if remaining <= 0 {
+				return 0, false
+			}
+			if timeout == 0 || remaining < timeout {
+				timeout = remaining
+			}
+		}
+		return timeout, true
+	}
 
 	for i, symbol := range target.symbols {
 		solutions[i] = solver.NewIndefinite(symbol.Type())
+		// Symbols of interface types must not be nil since the runner asserts their types.
+		if types.IsInterface(symbol.Type()) {
+			if solutions[i], ok = c.program.implementation(symbol.Type()); !ok {
+				return nil, errors.Errorf("no concrete type implementing %v was found", symbol.Type())
+			}
+		}
 	}
 
-	for i := uint(0); i < target.MaxExec; i++ {
+	// loops finds the loops to bound the iterations in which branches are negated,
+	// and the counting loops to be summarized.
+	loops := newLoops()
+	if target.SummarizeLoops {
+		backend.SetLoops(loops.counting)
+	}
+	// tree records the decisions of all the runs and the negations attempted so far.
+	tree := newPathTree()
+	// parent is the candidate whose negation produced the current solutions.
+	var parent *Candidate
+	// All the solvers are created by the backend.
+	// A solver is kept for each path so that the conditions of its prefixes are solved incrementally.
+	// It is closed once the strategy has no candidates of the path left, which are counted by pending.
+	solvers := make(map[*Path]solver.Solver)
+	pending := make(map[*Path]int)
+	release := func(path *Path) {
+		pending[path]--
+		if pending[path] == 0 {
+			solvers[path].Close()
+			delete(solvers, path)
+			delete(pending, path)
+		}
+	}
+	// The query being solved is interrupted when ctx is done.
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			backend.Interrupt()
+		case <-stop:
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+		for _, s := range solvers {
+			s.Close()
+		}
+		backend.Close()
+	}()
+
+	// runs is the number of runs including those with the inputs of overflows.
+	runs := uint(0)
+	for i := uint(0); runs < target.MaxExec; i++ {
+		if ctx.Err() != nil {
+			log.Info.Printf("[%d] stop because the execution is canceled: %v", i, ctx.Err())
+			break
+		}
 		values := make([]interface{}, n)
 		// Assign a zero value if the concrete value is nil.
 		for j, sol := range solutions {
@@ -105,82 +354,228 @@ func (c *Congo) Execute(funcName string) (*ExecuteResult, error) {
 		log.Info.Printf("[%d] run: %v", i, values)
 
 		// Interpret the program with the current symbol values.
-		result, err := c.Run(funcName, values)
-		if err != nil {
+		result, err := c.RunContext(ctx, funcName, values)
+		runs++
+		if ctx.Err() != nil {
+			// The run was aborted, so the trace is incomplete.
+			log.Info.Printf("[%d] stop because the execution is canceled: %v", i, ctx.Err())
+			break
+		}
+		if result.BudgetExceeded {
+			log.Info.Printf("[%d] %v", i, err)
+		} else if err != nil {
 			log.Info.Printf("[%d] panic", i)
 		}
 
-		// Update the covered blocks.
-		nNewCoveredBlks := 0
-		for _, instr := range result.Instrs {
-			b := instr.Block()
-			if b.Parent() == target.f {
-				if _, ok := covered[b]; !ok {
-					covered[b] = struct{}{}
-					nNewCoveredBlks++
-				}
-			}
+		path := &Path{
+			solutions: solutions,
+			instrs:    result.Instrs,
+			// The trace of a run that exceeded the budget is cut off after an instruction that was executed
+			// successfully, so it has no instruction that caused a panic.
+			complete: result.ExitCode == 0 || result.BudgetExceeded,
+		}
+		if parent != nil {
+			// The branches before the negated one are shared with the parent.
+			path.Bound = parent.Index + 1
+			path.Generation = parent.Path.Generation + 1
+		}
+		pathSolver, err := backend.NewSolver(target.symbols, c.program.concreteTypes, path.instrs, path.complete)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create a solver")
+		}
+		path.Branches = pathSolver.Branches()
+
+		// Update the covered blocks and edges.
+		path.NewBlocks, path.NewEdges = cov.update(path.instrs, path.Branches)
+		newCovered := path.NewBlocks
+		if target.Metric == "edge" {
+			newCovered = path.NewEdges
 		}
 
-		// Record the concrete values if new blocks are covered.
-		if nNewCoveredBlks > 0 {
+		// Record the concrete values if new blocks (or edges) are covered.
+		// The values that exceed the budget are also recorded so that the generated test reports them.
+		if newCovered > 0 || result.BudgetExceeded {
 			runResults = append(runResults, &RunResult{
-				symbolValues: values,
-				returnValues: result.Return,
-				panicked:     result.ExitCode != 0,
+				symbolValues:   values,
+				returnValues:   result.Return,
+				panicked:       result.ExitCode != 0 && !result.BudgetExceeded,
+				budgetExceeded: result.BudgetExceeded,
 			})
 		}
 
+		// Check whether the integer operations in the target package can overflow along the path.
+		if target.Overflow || target.OverflowTests {
+			overflowInstrs := pathSolver.Overflows()
+			for _, j := range overflowCandidates(overflowInstrs, target.f.Pkg, overflowed) {
+				timeout, ok := queryTimeout()
+				if !ok || ctx.Err() != nil {
+					break
+				}
+				backend.SetTimeout(timeout)
+				start := time.Now()
+				sols, err := pathSolver.SolveOverflow(j)
+				solverTime += time.Since(start)
+				if err != nil {
+					if _, ok := err.(solver.UnsatError); ok {
+						continue
+					}
+					if err, ok := err.(solver.UnknownError); ok {
+						log.Info.Printf("[%d] unknown overflow of %s (%s)", i, overflowInstrs[j], err.Reason)
+						continue
+					}
+					return nil, errors.Wrap(err, "failed to solve the condition of an overflow")
+				}
+				// The symbols not involved in the condition keep their values.
+				witness := make([]interface{}, n)
+				for k, sol := range sols {
+					witness[k] = values[k]
+					if sol != nil {
+						witness[k] = sol.Concretize(zero)
+					}
+				}
+				overflowed[overflowInstrs[j]] = true
+				overflow := newOverflow(overflowInstrs[j], witness)
+				overflows = append(overflows, overflow)
+				log.Info.Printf("[%d] overflow of %s at %s: %v", i, overflow.Instr, overflow.Pos, witness)
+
+				if target.OverflowTests && runs < target.MaxExec {
+					result, err := c.RunContext(ctx, funcName, witness)
+					runs++
+					if ctx.Err() != nil {
+						break
+					}
+					// The branches of the run are not known without a solver, so only the blocks and
+					// the edges of if statements are covered.
+					cov.update(result.Instrs, nil)
+					if err != nil && !result.BudgetExceeded {
+						log.Info.Printf("[%d] panic with the input of the overflow", i)
+					}
+					runResults = append(runResults, &RunResult{
+						symbolValues:   witness,
+						returnValues:   result.Return,
+						panicked:       result.ExitCode != 0 && !result.BudgetExceeded,
+						budgetExceeded: result.BudgetExceeded,
+					})
+				}
+			}
+		}
+
 		// Compute the coverage and exit if it exceeds the minCoverage.
-		// Also exit when the execution count minus one is equal to maxExec to avoid unnecessary constraint solver call.
-		coverage = float64(len(covered)) / float64(len(target.f.Blocks))
-		log.Info.Printf("[%d] coverage: %.3f", i, coverage)
+		// Also exit when the number of runs has reached maxExec to avoid unnecessary constraint solver call.
+		coverage = cov.blockCoverage()
+		if target.Metric == "edge" {
+			coverage = cov.edgeCoverage()
+		}
+		log.Info.Printf("[%d] coverage: %.3f (block %.3f, edge %.3f)", i, coverage, cov.blockCoverage(), cov.edgeCoverage())
 		if coverage >= target.MinCoverage {
 			log.Info.Printf("[%d] stop because the coverage criteria has been satisfied.", i)
+			pathSolver.Close()
 			break
 		}
 
-		if i == target.MaxExec-1 {
+		if runs >= target.MaxExec {
 			log.Info.Printf("[%d] stop because the runnign count has reached the limit", i)
+			pathSolver.Close()
+			break
 		}
 
-		z3Solver, err := solver.CreateZ3Solver(target.symbols, result.Instrs, result.ExitCode == 0)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create a solver")
-		}
-
-		branches := z3Solver.Branches()
-		queue, queueAfter := make([]int, 0), make([]int, 0)
-		for j := len(branches) - 1; j >= 0; j-- {
-			branch := branches[j]
-			switch branch := branch.(type) {
-			case *solver.BranchIf:
-				b := branch.Other()
-				if _, ok := covered[b]; !ok {
-					queue = append(queue, j)
-				} else {
-					queueAfter = append(queueAfter, j)
+		if tree.insert(path) {
+			iters := loops.iterations(path.instrs, pathSolver.Positions())
+			for j := path.Bound; j < len(path.Branches); j++ {
+				// The exit of a summarized loop is negated at any iteration since it solves the trip count.
+				_, summary := path.Branches[j].(*solver.BranchLoop)
+				if target.LoopBound > 0 && uint(iters[j]) > target.LoopBound && !summary {
+					log.Debug.Printf("[%d] skip %d (iteration %d of a loop)", i, j, iters[j])
+					continue
 				}
-			case *solver.BranchDeref:
-				queue = append(queue, j)
+				strategy.Push(Candidate{Path: path, Index: j})
+				pending[path]++
+			}
+			if pending[path] > 0 {
+				solvers[path] = pathSolver
+			} else {
+				pathSolver.Close()
 			}
+		} else {
+			log.Info.Printf("[%d] the path has been explored before", i)
+			pathSolver.Close()
 		}
-		queue = append(queue, queueAfter...)
 
-		for _, j := range queue {
-			log.Info.Printf("[%d] negate %d", i, j)
-			solutions, err = z3Solver.Solve(j)
+		parent = nil
+		for {
+			// No more queries are solved once the execution is canceled.
+			if ctx.Err() != nil {
+				break
+			}
+			cand, ok := strategy.Pop()
+			if !ok {
+				break
+			}
+			if tree.explored(cand) {
+				log.Debug.Printf("[%d] skip %d (generation %d)", i, cand.Index, cand.Path.Generation)
+				release(cand.Path)
+				continue
+			}
+			timeout, ok := queryTimeout()
+			if !ok {
+				break
+			}
+			backend.SetTimeout(timeout)
+
+			log.Info.Printf("[%d] negate %d (generation %d)", i, cand.Index, cand.Path.Generation)
+			var dumpName string
+			if dumper != nil {
+				if dumpName, err = dumper.dump(solvers[cand.Path], i, cand); err != nil {
+					return nil, err
+				}
+			}
+			start := time.Now()
+			sols, err := solvers[cand.Path].Solve(cand.Index)
+			solverTime += time.Since(start)
+			release(cand.Path)
+			if dumper != nil {
+				if err := dumper.result(dumpName, err); err != nil {
+					return nil, err
+				}
+			}
 			if err == nil {
-				log.Info.Printf("[%d] sat %d", i, j)
+				log.Info.Printf("[%d] sat %d", i, cand.Index)
+				tree.record(cand, negationSat)
+				// The symbols not involved in the negated constraints keep their values.
+				solutions = make([]solver.Solution, n)
+				for j, sol := range sols {
+					if sol == nil {
+						sol = cand.Path.solutions[j]
+					}
+					solutions[j] = sol
+				}
+				parent = &cand
 				break
 			} else if _, ok := err.(solver.UnsatError); ok {
-				log.Info.Printf("[%d] unsat %d", i, j)
+				log.Info.Printf("[%d] unsat %d", i, cand.Index)
+				tree.record(cand, negationUnsat)
+			} else if err, ok := err.(solver.UnknownError); ok {
+				if ctx.Err() != nil {
+					// The query was interrupted.
+					break
+				}
+				log.Info.Printf("[%d] unknown %d (%s)", i, cand.Index, err.Reason)
+				tree.record(cand, negationUnknown)
+				unknowns = append(unknowns, cand.Branch())
 			} else {
 				return nil, errors.Wrap(err, "failed to solve assertions")
 			}
 		}
-
-		z3Solver.Close()
+		if parent == nil {
+			if ctx.Err() != nil {
+				log.Info.Printf("[%d] stop because the execution is canceled: %v", i, ctx.Err())
+			} else if target.SolverBudget > 0 && solverTime >= target.SolverBudget {
+				log.Info.Printf("[%d] stop because the solver budget has been exhausted", i)
+			} else {
+				log.Info.Printf("[%d] stop because no branch is left to negate", i)
+			}
+			break
+		}
 	}
 
 	symbolTypes := make([]types.Type, n)
@@ -190,8 +585,13 @@ func (c *Congo) Execute(funcName string) (*ExecuteResult, error) {
 
 	return &ExecuteResult{
 		Coverage:           coverage,
+		BlockCoverage:      cov.blockCoverage(),
+		EdgeCoverage:       cov.edgeCoverage(),
 		SymbolTypes:        symbolTypes,
 		RunResults:         runResults,
+		Unknowns:           unknowns,
+		Overflows:          overflows,
+		inPackage:          c.program.inPackage,
 		runnerFile:         c.program.runnerFile,
 		runnerTypesInfo:    c.program.runnerTypesInfo,
 		runnerPackage:      c.program.runnerPackage.Pkg,
@@ -203,8 +603,32 @@ func (c *Congo) Execute(funcName string) (*ExecuteResult, error) {
 	}, nil
 }
 
+// implementation returns an indefinite solution of the interface type ty
+// whose dynamic type is the first concrete type implementing ty.
+func (p *Program) implementation(ty types.Type) (solver.Solution, bool) {
+	iface := ty.Underlying().(*types.Interface)
+	for _, t := range p.concreteTypes {
+		if !types.Implements(t, iface) {
+			continue
+		}
+		var sub solver.Solution = solver.NewIndefinite(t)
+		if ptr, ok := t.(*types.Pointer); ok {
+			// A nil pointer is not allowed as a dynamic value.
+			sub = solver.NewReference(ptr, solver.NewIndefinite(ptr.Elem()))
+		}
+		return solver.NewDynamic(ty, sub), true
+	}
+	return nil, false
+}
+
 // Run runs the program by the interpreter provided by interp module.
 func (c *Congo) Run(funcName string, values []interface{}) (*interp.CongoInterpResult, error) {
+	return c.RunContext(context.Background(), funcName, values)
+}
+
+// RunContext runs the program by the interpreter provided by interp module.
+// The interpretation is aborted when ctx is done.
+func (c *Congo) RunContext(ctx context.Context, funcName string, values []interface{}) (*interp.CongoInterpResult, error) {
 	target, ok := c.targets[funcName]
 	if !ok {
 		return nil, errors.Errorf("function %s does not exist", funcName)
@@ -221,10 +645,12 @@ func (c *Congo) Run(funcName string, values []interface{}) (*interp.CongoInterpR
 	interp.CapturedOutput = new(bytes.Buffer)
 	mode := interp.DisableRecover // interp.EnableTracing
 	return interp.Interpret(
+		ctx,
 		c.program.runnerPackage,
 		target.f,
 		target.runnerName,
 		symbolValues,
+		interp.Limits{MaxSteps: target.MaxSteps, MaxTrace: target.MaxTrace},
 		mode,
 		&types.StdSizes{WordSize: 8, MaxAlign: 8},
 		"",
@@ -240,9 +666,12 @@ func (c *Congo) DumpRunnerAST(dest io.Writer) error {
 // DumpSSA dumps the SSA-format code into dest.
 func (c *Congo) DumpSSA(dest io.Writer) error {
 	var err error
-	_, err = c.program.runnerPackage.Func("main").WriteTo(dest)
-	if err != nil {
-		return err
+	// The in-package runner has no main function.
+	if mainFunc := c.program.runnerPackage.Func("main"); mainFunc != nil && !c.program.inPackage {
+		_, err = mainFunc.WriteTo(dest)
+		if err != nil {
+			return err
+		}
 	}
 
 	for _, target := range c.targets {
@@ -275,10 +704,18 @@ func (c *Congo) Target(name string) *Target {
 // ReturnValues has type []interp.value so it is meaningless to make this property public.
 // We use reflection to extract values from interp.value for now.
 type ExecuteResult struct {
-	Coverage    float64 // achieved coverage.
+	Coverage    float64 // achieved coverage in the metric of the execute option.
 	SymbolTypes []types.Type
 	RunResults  []*RunResult
-
+	// BlockCoverage and EdgeCoverage are the achieved coverage in each metric.
+	BlockCoverage float64
+	EdgeCoverage  float64
+	// Unknowns are the branches whose negations could not be decided by the solver (e.g., timed out).
+	Unknowns []solver.Branch
+	// Overflows are the integer overflows found with the Overflow option.
+	Overflows []Overflow
+
+	inPackage          bool
 	runnerFile         *ast.File
 	runnerTypesInfo    *types.Info
 	runnerPackage      *types.Package
@@ -294,4 +731,6 @@ type RunResult struct {
 	symbolValues []interface{}
 	returnValues interface{}
 	panicked     bool
+	// budgetExceeded reports whether the run was aborted because it exceeded the limits of the interpreter.
+	budgetExceeded bool
 }
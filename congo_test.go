@@ -1,8 +1,16 @@
 package congo
 
 import (
+	"bytes"
 	"fmt"
+	"go/format"
+	"go/token"
+	"math/big"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/ajalab/congo/solver"
 )
 
 const testPackage = "./testdata"
@@ -27,3 +35,121 @@ func TestExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestDivZero(t *testing.T) {
+	c, err := Load(&Config{}, testPackage)
+	if err != nil {
+		t.Fatalf("Config.Open: %v\n", err)
+	}
+	res, err := c.Execute("DivZero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var panicked *RunResult
+	for _, r := range res.RunResults {
+		if r.panicked {
+			panicked = r
+			break
+		}
+	}
+	if panicked == nil {
+		t.Fatal("no run panicked by division by zero")
+	}
+
+	// The trace of the panicked run has the failing side of the division as its last branch.
+	target := c.Target("DivZero")
+	result, err := c.Run("DivZero", panicked.symbolValues)
+	if err == nil || result.ExitCode == 0 {
+		t.Fatalf("the run with %v did not panic", panicked.symbolValues)
+	}
+	backend, err := solver.NewBackend(target.Solver, target.SolverCommand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	s, err := backend.NewSolver(target.symbols, c.program.concreteTypes, result.Instrs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	branches := s.Branches()
+	if len(branches) == 0 {
+		t.Fatal("the panicked run has no branch")
+	}
+	if _, ok := branches[len(branches)-1].(*solver.BranchDivZero); !ok {
+		t.Errorf("expected the last branch to be *solver.BranchDivZero, actual %T", branches[len(branches)-1])
+	}
+
+	// The generated test expects the panicked run to panic.
+	f, err := res.GenerateTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), f); err != nil {
+		t.Fatal(err)
+	}
+	test := buf.String()
+	for _, want := range []string{"defer func()", "if r := recover(); (r != nil) != tc." + panicFieldName} {
+		if !strings.Contains(test, want) {
+			t.Errorf("the generated test does not contain %q:\n%s", want, test)
+		}
+	}
+}
+
+// bigInt returns the value of an integer v as *big.Int.
+func bigInt(v interface{}) *big.Int {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(rv.Uint())
+	}
+	return big.NewInt(rv.Int())
+}
+
+func TestOverflow(t *testing.T) {
+	c, err := Load(&Config{}, testPackage)
+	if err != nil {
+		t.Fatalf("Config.Open: %v\n", err)
+	}
+
+	// exact computes the result of each function in arbitrary precision.
+	tcs := []struct {
+		name  string
+		exact func(vs []*big.Int) *big.Int
+	}{
+		{"TotalPrice", func(vs []*big.Int) *big.Int { return new(big.Int).Mul(vs[0], vs[1]) }},
+		{"AddInt8", func(vs []*big.Int) *big.Int { return new(big.Int).Add(vs[0], vs[1]) }},
+		{"SubInt16", func(vs []*big.Int) *big.Int { return new(big.Int).Sub(vs[0], vs[1]) }},
+		{"MulInt32", func(vs []*big.Int) *big.Int { return new(big.Int).Mul(vs[0], vs[1]) }},
+		{"ShlInt64", func(vs []*big.Int) *big.Int { return new(big.Int).Lsh(vs[0], uint(vs[1].Uint64())) }},
+		{"NegInt8", func(vs []*big.Int) *big.Int { return new(big.Int).Neg(vs[0]) }},
+		{"NarrowInt64", func(vs []*big.Int) *big.Int { return vs[0] }},
+		{"Int8ToUint16", func(vs []*big.Int) *big.Int { return vs[0] }},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := c.Execute(tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Overflows) == 0 {
+				t.Fatal("no overflow was found")
+			}
+			// The result of the concrete run with the input of each overflow differs from the exact one.
+			for _, of := range res.Overflows {
+				result, err := c.Run(tc.name, of.Values)
+				if err != nil {
+					t.Fatalf("the run with %v failed: %v", of.Values, err)
+				}
+				vs := make([]*big.Int, len(of.Values))
+				for i, v := range of.Values {
+					vs[i] = bigInt(v)
+				}
+				if actual, exact := bigInt(result.Return), tc.exact(vs); actual.Cmp(exact) == 0 {
+					t.Errorf("%s at %s does not overflow with %v: %v", of.Instr, of.Pos, of.Values, actual)
+				}
+			}
+		})
+	}
+}
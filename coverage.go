@@ -0,0 +1,102 @@
+package congo
+
+import (
+	"github.com/ajalab/congo/solver"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ssa"
+)
+
+// checkMetric returns an error if metric is not a coverage metric.
+// The available metrics are "block" and "edge".
+func checkMetric(metric string) error {
+	switch metric {
+	case "block", "edge":
+		return nil
+	}
+	return errors.Errorf("unknown coverage metric: %s", metric)
+}
+
+// coverage records the blocks and the edges of the target function covered by runs.
+// An edge is an outcome of a branching instruction, which is represented by decision:
+// each side of an If instruction, and the success or panic (the decision to nil) of
+// a dereference of a symbolic pointer or a division by a symbolic integer.
+type coverage struct {
+	target *ssa.Function
+	blocks map[*ssa.BasicBlock]struct{}
+	edges  map[decision]struct{}
+	// checks is the set of the dereferences and the divisions that may panic found so far.
+	// Since it is not known statically which of them may panic,
+	// their edges are counted after they are found in a run.
+	checks map[ssa.Instruction]struct{}
+	nEdges int
+}
+
+func newCoverage(target *ssa.Function) *coverage {
+	c := &coverage{
+		target: target,
+		blocks: make(map[*ssa.BasicBlock]struct{}),
+		edges:  make(map[decision]struct{}),
+		checks: make(map[ssa.Instruction]struct{}),
+	}
+	for _, b := range target.Blocks {
+		if _, ok := b.Instrs[len(b.Instrs)-1].(*ssa.If); ok {
+			c.nEdges += 2
+		}
+	}
+	return c
+}
+
+// update adds the blocks and the edges covered by the run of the trace instrs with branches,
+// and returns the numbers of the blocks and the edges first covered by the run.
+func (c *coverage) update(instrs []ssa.Instruction, branches []solver.Branch) (int, int) {
+	newBlocks, newEdges := 0, 0
+	addEdge := func(d decision) {
+		if _, ok := c.edges[d]; !ok {
+			c.edges[d] = struct{}{}
+			newEdges++
+		}
+	}
+	for i, instr := range instrs {
+		b := instr.Block()
+		if b.Parent() != c.target {
+			continue
+		}
+		if _, ok := c.blocks[b]; !ok {
+			c.blocks[b] = struct{}{}
+			newBlocks++
+		}
+		if _, ok := instr.(*ssa.If); ok && i+1 < len(instrs) {
+			addEdge(decision{instr: instr, to: instrs[i+1].Block()})
+		}
+	}
+	for _, branch := range branches {
+		switch branch.(type) {
+		case *solver.BranchDeref, *solver.BranchDivZero:
+		default:
+			continue
+		}
+		if branch.Instr().Parent() != c.target {
+			continue
+		}
+		if _, ok := c.checks[branch.Instr()]; !ok {
+			c.checks[branch.Instr()] = struct{}{}
+			c.nEdges += 2
+		}
+		addEdge(decisionOf(branch))
+	}
+	return newBlocks, newEdges
+}
+
+// blockCoverage returns the ratio of the covered blocks.
+func (c *coverage) blockCoverage() float64 {
+	return float64(len(c.blocks)) / float64(len(c.target.Blocks))
+}
+
+// edgeCoverage returns the ratio of the covered edges.
+// It is 1 if the target function has no edge.
+func (c *coverage) edgeCoverage() float64 {
+	if c.nEdges == 0 {
+		return 1
+	}
+	return float64(len(c.edges)) / float64(c.nEdges)
+}
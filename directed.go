@@ -0,0 +1,148 @@
+package congo
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// directedStrategy is a strategy that negates first the branch whose untaken side is
+// closest to blocks not visited yet.
+// The distance is the length of the shortest path on the control flow graph of the target function
+// and the functions in the same package that it calls statically, where a call is an edge from the block
+// of the call instruction to the entry block of the callee.
+// If edges is true, the edges not covered yet are also targeted: the distance of a branch is 0
+// if its negation covers a new edge, and blocks with an outgoing edge not covered are regarded as not visited.
+// Ties are broken as in the depth-first search.
+type directedStrategy struct {
+	target     *ssa.Function
+	candidates []Candidate
+	edges      bool
+
+	// funcs is the set of functions in the control flow graph.
+	funcs map[*ssa.Function]struct{}
+	// callers maps the entry block of each function in funcs to the blocks calling it.
+	callers map[*ssa.BasicBlock][]*ssa.BasicBlock
+	pathCoverage
+	// dist is the distance from each block to the nearest block not visited.
+	// Blocks from which no such block is reachable are not contained.
+	dist map[*ssa.BasicBlock]int
+}
+
+func newDirectedStrategy(target *ssa.Function, edges bool) *directedStrategy {
+	s := &directedStrategy{
+		target:       target,
+		edges:        edges,
+		funcs:        make(map[*ssa.Function]struct{}),
+		callers:      make(map[*ssa.BasicBlock][]*ssa.BasicBlock),
+		pathCoverage: newPathCoverage(),
+	}
+	s.addFunc(target)
+	return s
+}
+
+// addFunc adds f and the functions reachable from f by static calls in the same package to the graph.
+func (s *directedStrategy) addFunc(f *ssa.Function) {
+	if _, ok := s.funcs[f]; ok || len(f.Blocks) == 0 || f.Pkg != s.target.Pkg {
+		return
+	}
+	s.funcs[f] = struct{}{}
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			callee := call.Common().StaticCallee()
+			if callee == nil {
+				continue
+			}
+			s.addFunc(callee)
+			if _, ok := s.funcs[callee]; ok {
+				entry := callee.Blocks[0]
+				s.callers[entry] = append(s.callers[entry], b)
+			}
+		}
+	}
+}
+
+// update recomputes the distances after the blocks visited have changed.
+func (s *directedStrategy) update() {
+	// Compute the distances by a breadth-first search from the blocks not visited
+	// on the reversed graph.
+	s.dist = make(map[*ssa.BasicBlock]int)
+	var queue []*ssa.BasicBlock
+	for f := range s.funcs {
+		for _, b := range f.Blocks {
+			if !s.reached(b) {
+				s.dist[b] = 0
+				queue = append(queue, b)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		for _, preds := range [][]*ssa.BasicBlock{b.Preds, s.callers[b]} {
+			for _, pred := range preds {
+				if _, ok := s.dist[pred]; !ok {
+					s.dist[pred] = s.dist[b] + 1
+					queue = append(queue, pred)
+				}
+			}
+		}
+	}
+}
+
+// reached reports whether b has been visited (and all the edges from b have been covered if s.edges is true).
+func (s *directedStrategy) reached(b *ssa.BasicBlock) bool {
+	if _, ok := s.visited[b]; !ok {
+		return false
+	}
+	if ifInstr, ok := b.Instrs[len(b.Instrs)-1].(*ssa.If); ok && s.edges {
+		for _, succ := range b.Succs {
+			if _, ok := s.covered[decision{instr: ifInstr, to: succ}]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// distance returns the distance of the candidate c, or false if it is unknown.
+func (s *directedStrategy) distance(c Candidate) (int, bool) {
+	if s.edges {
+		if _, ok := s.covered[decision{instr: c.Branch().Instr(), to: c.Branch().Other()}]; !ok {
+			return 0, true
+		}
+	}
+	b := c.Branch().Other()
+	if b == nil {
+		return 0, false
+	}
+	d, ok := s.dist[b]
+	return d, ok
+}
+
+func (s *directedStrategy) Push(c Candidate) {
+	if s.add(c.Path) {
+		s.update()
+	}
+	s.candidates = append(s.candidates, c)
+}
+
+func (s *directedStrategy) Pop() (Candidate, bool) {
+	n := len(s.candidates)
+	if n == 0 {
+		return Candidate{}, false
+	}
+	i := n - 1
+	d, ok := s.distance(s.candidates[i])
+	for j := n - 2; j >= 0; j-- {
+		dj, okj := s.distance(s.candidates[j])
+		if okj && (!ok || dj < d) {
+			i, d, ok = j, dj, okj
+		}
+	}
+	c := s.candidates[i]
+	s.candidates = append(s.candidates[:i], s.candidates[i+1:]...)
+	return c, true
+}
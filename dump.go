@@ -0,0 +1,65 @@
+package congo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/ajalab/congo/solver"
+	"github.com/pkg/errors"
+)
+
+// queryDumper writes the queries for a target function to SMT-LIB2 files in a directory.
+type queryDumper struct {
+	dir    string
+	prefix string
+	count  int
+}
+
+func newQueryDumper(dir, funcName string) (*queryDumper, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the directory to dump queries")
+	}
+	// Function names such as (*T).M are made safe for file names.
+	prefix := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, funcName)
+	return &queryDumper{dir: dir, prefix: prefix}, nil
+}
+
+// dump writes the query that negates the branch of cand to a new file and returns the file name.
+// The query is written before it is solved so that the queries that never return can be inspected.
+func (d *queryDumper) dump(s solver.Solver, i uint, cand Candidate) (string, error) {
+	name := filepath.Join(d.dir, fmt.Sprintf("%s-%04d.smt2", d.prefix, d.count))
+	d.count++
+	f, err := os.Create(name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to dump a query")
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "; query of %s at run %d: negate %d (generation %d)\n", d.prefix, i, cand.Index, cand.Path.Generation)
+	if err := s.Dump(f, cand.Index); err != nil {
+		return "", errors.Wrap(err, "failed to dump a query")
+	}
+	return name, nil
+}
+
+// result appends the result of the query to the file name.
+func (d *queryDumper) result(name string, err error) error {
+	f, ferr := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0)
+	if ferr != nil {
+		return errors.Wrap(ferr, "failed to dump the result of a query")
+	}
+	defer f.Close()
+	result := "sat"
+	if err != nil {
+		result = err.Error()
+	}
+	_, ferr = fmt.Fprintf(f, "; result: %s\n", result)
+	return ferr
+}
@@ -1,20 +1,25 @@
 package congo
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
 	"go/format"
 	"go/token"
 	"go/types"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/packages"
 )
 
 func generateRunner(targetPackage *packages.Package, targets map[string]*Target) (string, error) {
-	runnerFile, err := generateRunnerAST(targetPackage, targets)
+	runnerFile, err := generateRunnerAST(targetPackage, targets, false)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to generate runner AST file")
 	}
@@ -32,14 +37,41 @@ func generateRunner(targetPackage *packages.Package, targets map[string]*Target)
 	return runnerPackageFPath, nil
 }
 
+// inPackageRunnerFileName is the name of the runner file placed in the target package.
+const inPackageRunnerFileName = "zz_congo_runner.go"
+
+// generateInPackageRunner generates a runner that belongs to the target package.
+// It returns the path and the content of the runner file,
+// which is supposed to be given to the loader as an overlay.
+func generateInPackageRunner(targetPackage *packages.Package, targets map[string]*Target) (string, []byte, error) {
+	if len(targetPackage.GoFiles) == 0 {
+		return "", nil, errors.Errorf("package %s has no Go files", targetPackage.PkgPath)
+	}
+	runnerFile, err := generateRunnerAST(targetPackage, targets, true)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to generate runner AST file")
+	}
+	runnerPackageFPath := filepath.Join(filepath.Dir(targetPackage.GoFiles[0]), inPackageRunnerFileName)
+	if _, err := os.Stat(runnerPackageFPath); err == nil {
+		return "", nil, errors.Errorf("%s already exists", runnerPackageFPath)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), runnerFile); err != nil {
+		return "", nil, err
+	}
+	return runnerPackageFPath, buf.Bytes(), nil
+}
+
 // generateRunner generates the AST of a test runner.
 // The runner calls the target function declared in targetPackage.
-func generateRunnerAST(targetPackage *packages.Package, targets map[string]*Target) (*ast.File, error) {
+// If inPackage is true, the runner belongs to targetPackage so that it can call unexported functions.
+func generateRunnerAST(targetPackage *packages.Package, targets map[string]*Target, inPackage bool) (*ast.File, error) {
 	scope := ast.NewScope(nil)
 	runnerFuncDecls := make([]*ast.FuncDecl, len(targets))
 	i := 0
 	for _, target := range targets {
-		runnerFuncDecl, err := generateRunnerFuncAST(targetPackage, target.name)
+		runnerFuncDecl, err := generateRunnerFuncAST(targetPackage, target.name, inPackage)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to generate a runner function declaration AST for %s", target.name)
 		}
@@ -83,6 +115,26 @@ func generateRunnerAST(targetPackage *packages.Package, targets map[string]*Targ
 			},
 		},
 	}
+	if inPackage {
+		// The in-package runner does not need the main function.
+		// The interpreter directly calls the runner functions.
+		decls := []ast.Decl{
+			generateImportDeclAST("", congoSymbolPackagePath),
+			// runtime package is required to run by interp
+			generateImportDeclAST("_", "runtime"),
+		}
+		for _, decl := range runnerFuncDecls {
+			decls = append(decls, decl)
+		}
+		f := &ast.File{
+			Scope: scope,
+			Name:  ast.NewIdent(targetPackage.Name),
+			Decls: decls,
+		}
+		unqualify(f, targetPackage.Name)
+		return f, nil
+	}
+
 	mainFuncDeclObj := ast.NewObj(ast.Fun, mainFuncDecl.Name.Name)
 	mainFuncDeclObj.Decl = mainFuncDecl
 	scope.Insert(mainFuncDeclObj)
@@ -108,24 +160,24 @@ func generateRunnerAST(targetPackage *packages.Package, targets map[string]*Targ
 	}, nil
 }
 
-// Get the signature of the function that belongs to pkg
+// Get the signature of the function (or the method) that belongs to pkg
 func getTargetFuncSig(pkg *packages.Package, funcName string) (*types.Signature, error) {
-	targetFunc := pkg.Types.Scope().Lookup(funcName)
-	if targetFunc == nil {
-		return nil, errors.Errorf("function %s does not exist in package %s", funcName, pkg)
-	}
-	targetFuncType := targetFunc.Type()
-	sig, ok := targetFuncType.(*types.Signature)
-	if !ok {
-		return nil, errors.Errorf("%s is not a function", funcName)
+	targetFunc, err := lookupTargetFunc(pkg.Types, funcName)
+	if err != nil {
+		return nil, err
 	}
-
-	return sig, nil
+	return targetFunc.Type().(*types.Signature), nil
 }
 
 const runnerFuncNamePrefix = "__congoRunner"
 
-func generateRunnerFuncAST(targetPackage *packages.Package, funcName string) (*ast.FuncDecl, error) {
+// funcIdent converts the name of a target function into an identifier.
+// The name of a method "T.M" is converted into "T_M".
+func funcIdent(funcName string) string {
+	return strings.Replace(funcName, ".", "_", -1)
+}
+
+func generateRunnerFuncAST(targetPackage *packages.Package, funcName string, inPackage bool) (*ast.FuncDecl, error) {
 	// Get the signature of the target function
 	sig, err := getTargetFuncSig(targetPackage, funcName)
 	if err != nil {
@@ -134,12 +186,26 @@ func generateRunnerFuncAST(targetPackage *packages.Package, funcName string) (*a
 
 	// Generate AST of the function call to the target function
 	// targetPackage.targetFunc(arg0, arg1, ...)
+	// or the method call with a symbolic receiver
+	// recv.targetMethod(arg0, arg1, ...)
 	args := generateSymbolASTs(sig)
+	var fun ast.Expr = &ast.SelectorExpr{
+		X:   ast.NewIdent(targetPackage.Name),
+		Sel: ast.NewIdent(funcName),
+	}
+	if inPackage {
+		fun = ast.NewIdent(funcName)
+	}
+	if sig.Recv() != nil {
+		_, methodName := splitFuncName(funcName)
+		fun = &ast.SelectorExpr{
+			X:   args[0],
+			Sel: ast.NewIdent(methodName),
+		}
+		args = args[1:]
+	}
 	funcCallExpr := &ast.CallExpr{
-		Fun: &ast.SelectorExpr{
-			X:   ast.NewIdent(targetPackage.Name),
-			Sel: ast.NewIdent(funcName),
-		},
+		Fun:  fun,
 		Args: args,
 	}
 
@@ -234,7 +300,7 @@ func generateRunnerFuncAST(targetPackage *packages.Package, funcName string) (*a
 	//     (runnerFuncBody)
 	// }
 	runnerFuncDecl := &ast.FuncDecl{
-		Name: ast.NewIdent(runnerFuncNamePrefix + funcName),
+		Name: ast.NewIdent(runnerFuncNamePrefix + funcIdent(funcName)),
 		Type: &ast.FuncType{},
 		Body: runnerFuncBody,
 	}
@@ -242,12 +308,18 @@ func generateRunnerFuncAST(targetPackage *packages.Package, funcName string) (*a
 	return runnerFuncDecl, nil
 }
 
+// generateSymbolASTs generates symbols for the parameters of sig.
+// If sig has a receiver, the first symbol is for the receiver.
 func generateSymbolASTs(sig *types.Signature) []ast.Expr {
-	argTypes := sig.Params()
-	argLen := argTypes.Len()
+	var argTypes []types.Type
+	if recv := sig.Recv(); recv != nil {
+		argTypes = append(argTypes, recv.Type())
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		argTypes = append(argTypes, sig.Params().At(i).Type())
+	}
 	var args []ast.Expr
-	for i := 0; i < argLen; i++ {
-		ty := argTypes.At(i).Type()
+	for i, ty := range argTypes {
 		args = append(args, &ast.TypeAssertExpr{
 			X: &ast.IndexExpr{
 				X: &ast.SelectorExpr{
@@ -284,3 +356,19 @@ func generateImportDeclAST(name, path string) *ast.GenDecl {
 		},
 	}
 }
+
+// unqualify removes the qualifier pkgName from selector expressions in node
+// so that node can refer to the members of the package from inside.
+func unqualify(node ast.Node, pkgName string) {
+	astutil.Apply(node, func(c *astutil.Cursor) bool {
+		sel, ok := c.Node().(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok && x.Name == pkgName {
+			c.Replace(sel.Sel)
+			return false
+		}
+		return true
+	}, nil)
+}
@@ -8,8 +8,12 @@ import (
 	"go/token"
 	"go/types"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/ajalab/congo/interp"
+
 	"github.com/pkg/errors"
 	"golang.org/x/tools/go/ast/astutil"
 )
@@ -37,7 +41,11 @@ func (r *ExecuteResult) GenerateTest() (*ast.File, error) {
 	}
 
 	// Now we prepare the AST file for test to generate
-	testFuncName := "Test" + strings.Title(r.targetFuncName)
+	testFuncName := "Test" + strings.Title(funcIdent(r.targetFuncName))
+	testPackageName := r.targetPackage.Name() + "_test"
+	if r.inPackage {
+		testPackageName = r.targetPackage.Name()
+	}
 	testTemp := fmt.Sprintf(`
 		package %s
 
@@ -49,7 +57,7 @@ func (r *ExecuteResult) GenerateTest() (*ast.File, error) {
 				})
 			}
 		}
-	`, r.targetPackage.Name()+"_test", testFuncName, testingT)
+	`, testPackageName, testFuncName, testingT)
 
 	fset := token.NewFileSet()
 	testFileName := "test.go"
@@ -59,7 +67,9 @@ func (r *ExecuteResult) GenerateTest() (*ast.File, error) {
 	}
 	astutil.AddImport(fset, f, "fmt")
 	astutil.AddImport(fset, f, "testing")
-	astutil.AddImport(fset, f, r.targetPackage.Path())
+	if !r.inPackage {
+		astutil.AddImport(fset, f, r.targetPackage.Path())
+	}
 
 	// Add symbol value fields to the struct type for test cases (testCasesType)
 	testFuncDecl := f.Scope.Lookup(testFuncName).Decl.(*ast.FuncDecl)
@@ -80,6 +90,30 @@ func (r *ExecuteResult) GenerateTest() (*ast.File, error) {
 		})
 	}
 
+	// Add a field to mark the test cases whose runs exceeded the budget of the interpreter
+	budgetExceeded := false
+	for _, runResult := range r.RunResults {
+		budgetExceeded = budgetExceeded || runResult.budgetExceeded
+	}
+	if budgetExceeded {
+		testCasesType.Fields.List = append(testCasesType.Fields.List, &ast.Field{
+			Type:  ast.NewIdent("bool"),
+			Names: []*ast.Ident{ast.NewIdent(budgetExceededFieldName)},
+		})
+	}
+
+	// Add a field to mark the test cases whose runs panicked (e.g., by a division by zero)
+	panicked := false
+	for _, runResult := range r.RunResults {
+		panicked = panicked || runResult.panicked
+	}
+	if panicked {
+		testCasesType.Fields.List = append(testCasesType.Fields.List, &ast.Field{
+			Type:  ast.NewIdent("bool"),
+			Names: []*ast.Ident{ast.NewIdent(panicFieldName)},
+		})
+	}
+
 	// Add test cases
 	for _, runResult := range r.RunResults {
 		// Add symbol values
@@ -94,6 +128,12 @@ func (r *ExecuteResult) GenerateTest() (*ast.File, error) {
 		returnValues := runResult.returnValues
 		returnValuesLen := r.targetFuncSig.Results().Len()
 		switch {
+		case runResult.budgetExceeded, runResult.panicked:
+			// The run did not return. The test case is skipped or expected to panic, so the oracle values are zero.
+			for j := 0; j < returnValuesLen; j++ {
+				ty := r.targetFuncSig.Results().At(j).Type()
+				tc.Elts = append(tc.Elts, value2ASTExpr(zero(ty), ty))
+			}
 		case returnValuesLen == 1:
 			value := reflect.ValueOf(returnValues).Interface()
 			ty := r.targetFuncSig.Results().At(0).Type()
@@ -106,40 +146,254 @@ func (r *ExecuteResult) GenerateTest() (*ast.File, error) {
 			}
 		}
 
+		if budgetExceeded {
+			tc.Elts = append(tc.Elts, ast.NewIdent(strconv.FormatBool(runResult.budgetExceeded)))
+		}
+		if panicked {
+			tc.Elts = append(tc.Elts, ast.NewIdent(strconv.FormatBool(runResult.panicked)))
+		}
+
 		testCasesExpr.Elts = append(testCasesExpr.Elts, tc)
 	}
 	testRangeStmtBody := testFuncDecl.Body.List[1].(*ast.RangeStmt).Body
 	testRunCallExpr := testRangeStmtBody.List[0].(*ast.ExprStmt).X.(*ast.CallExpr)
 	testRunFuncExpr := testRunCallExpr.Args[1].(*ast.FuncLit)
 	testRunFuncExpr.Body.List = runnerFunc.Body.List
+	if panicked {
+		testRunFuncExpr.Body.List = append([]ast.Stmt{expectPanic(testingT)}, testRunFuncExpr.Body.List...)
+	}
+	if budgetExceeded {
+		testRunFuncExpr.Body.List = append([]ast.Stmt{skipBudgetExceeded(testingT)}, testRunFuncExpr.Body.List...)
+	}
 	r.insertAuxiliaryFuncs(f)
 
+	// Types and values in the target package are referred without the qualifier.
+	if r.inPackage {
+		unqualify(f, r.targetPackage.Name())
+	}
+
+	// Values such as NaN are expressed with functions in the math package.
+	if refersToPackage(f, "math") {
+		astutil.AddImport(fset, f, "math")
+	}
+
+	// Types in other packages (e.g., dynamic types of interface values) are referred with their qualifiers.
+	for _, pkg := range r.referredPackages() {
+		if pkg != r.targetPackage && refersToPackage(f, pkg.Name()) {
+			astutil.AddImport(fset, f, pkg.Path())
+		}
+	}
+
 	return f, nil
 }
 
-func (r *ExecuteResult) insertAuxiliaryFuncs(f *ast.File) {
-	insertFuncs := make(map[string]*ast.FuncDecl)
-
+// referredPackages returns the packages of the named types that may be referred in the test code,
+// i.e., those in the types of the symbols and the return values, and the dynamic types of the symbol values.
+// They are sorted by their import paths.
+func (r *ExecuteResult) referredPackages() []*types.Package {
+	pkgs := make(map[string]*types.Package)
+	visited := make(map[types.Type]bool)
 	for i, ty := range r.SymbolTypes {
-		pointerTy, ok := ty.(*types.Pointer)
-		if !ok {
-			continue
+		addTypePackages(pkgs, ty, visited)
+		for _, rr := range r.RunResults {
+			addValuePackages(pkgs, rr.symbolValues[i], ty, visited)
 		}
-		elemTy, ok := pointerTy.Elem().(*types.Basic)
-		if !ok {
-			continue
+	}
+	for i := 0; i < r.targetFuncSig.Results().Len(); i++ {
+		addTypePackages(pkgs, r.targetFuncSig.Results().At(i).Type(), visited)
+	}
+
+	paths := make([]string, 0, len(pkgs))
+	for path := range pkgs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	sorted := make([]*types.Package, len(paths))
+	for i, path := range paths {
+		sorted[i] = pkgs[path]
+	}
+	return sorted
+}
+
+// addTypePackages adds the packages of the named types in ty to pkgs.
+func addTypePackages(pkgs map[string]*types.Package, ty types.Type, visited map[types.Type]bool) {
+	if visited[ty] {
+		return
+	}
+	visited[ty] = true
+	switch ty := ty.(type) {
+	case *types.Named:
+		if pkg := ty.Obj().Pkg(); pkg != nil {
+			pkgs[pkg.Path()] = pkg
 		}
-		name := elemTy.Name() + "ptr"
-		if _, ok := insertFuncs[name]; ok {
-			continue
+		addTypePackages(pkgs, ty.Underlying(), visited)
+	case *types.Pointer:
+		addTypePackages(pkgs, ty.Elem(), visited)
+	case *types.Struct:
+		for i := 0; i < ty.NumFields(); i++ {
+			addTypePackages(pkgs, ty.Field(i).Type(), visited)
 		}
-		for _, rr := range r.RunResults {
-			v := rr.symbolValues[i]
-			if v != nil {
-				insertFuncs[name] = getAuxiliaryPtrFunc(name, elemTy)
-				break
+	case *types.Slice:
+		addTypePackages(pkgs, ty.Elem(), visited)
+	case *types.Array:
+		addTypePackages(pkgs, ty.Elem(), visited)
+	case *types.Map:
+		addTypePackages(pkgs, ty.Key(), visited)
+		addTypePackages(pkgs, ty.Elem(), visited)
+	}
+}
+
+// addValuePackages adds the packages of the dynamic types of the interface values in the value v of type ty to pkgs.
+func addValuePackages(pkgs map[string]*types.Package, v interface{}, ty types.Type, visited map[types.Type]bool) {
+	switch ty := ty.(type) {
+	case *types.Pointer:
+		p := reflect.ValueOf(v)
+		if !p.IsValid() || p.IsNil() {
+			return
+		}
+		addValuePackages(pkgs, p.Elem().Interface(), ty.Elem(), visited)
+	case *types.Named:
+		addValuePackages(pkgs, v, ty.Underlying(), visited)
+	case *types.Struct:
+		fields := reflect.ValueOf(v)
+		for i := 0; i < ty.NumFields(); i++ {
+			addValuePackages(pkgs, fields.Index(i).Interface(), ty.Field(i).Type(), visited)
+		}
+	case *types.Slice:
+		elems := reflect.ValueOf(v)
+		for i := 0; i < elems.Len(); i++ {
+			addValuePackages(pkgs, elems.Index(i).Interface(), ty.Elem(), visited)
+		}
+	case *types.Array:
+		elems := reflect.ValueOf(v)
+		for i := 0; i < elems.Len(); i++ {
+			addValuePackages(pkgs, elems.Index(i).Interface(), ty.Elem(), visited)
+		}
+	case *types.Interface:
+		if d, ok := v.(interp.SymbolicValue); ok {
+			addTypePackages(pkgs, d.Type, visited)
+			addValuePackages(pkgs, d.Value, d.Type, visited)
+		}
+	case *types.Map:
+		entries := reflect.ValueOf(v)
+		if entries.Kind() != reflect.Map {
+			return
+		}
+		for _, k := range entries.MapKeys() {
+			addValuePackages(pkgs, k.Interface(), ty.Key(), visited)
+			addValuePackages(pkgs, entries.MapIndex(k).Interface(), ty.Elem(), visited)
+		}
+	}
+}
+
+// budgetExceededFieldName is the name of the field of test cases
+// that reports whether the run exceeded the budget of the interpreter.
+const budgetExceededFieldName = "congoBudgetExceeded"
+
+// skipBudgetExceeded returns the statement that skips the test case if its run exceeded the budget,
+// which means the target function may not terminate with the input.
+func skipBudgetExceeded(testingT string) ast.Stmt {
+	return &ast.IfStmt{
+		Cond: &ast.SelectorExpr{
+			X:   ast.NewIdent("tc"),
+			Sel: ast.NewIdent(budgetExceededFieldName),
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ExprStmt{
+					X: &ast.CallExpr{
+						Fun: &ast.SelectorExpr{
+							X:   ast.NewIdent(testingT),
+							Sel: ast.NewIdent("Skip"),
+						},
+						Args: []ast.Expr{&ast.BasicLit{
+							Kind:  token.STRING,
+							Value: "\"the execution exceeded the budget of congo\"",
+						}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// panicFieldName is the name of the field of test cases
+// that reports whether the run is expected to panic.
+const panicFieldName = "congoPanic"
+
+// expectPanic returns the statement that checks whether the test case panics if and only if it is expected to.
+func expectPanic(testingT string) ast.Stmt {
+	want := &ast.SelectorExpr{
+		X:   ast.NewIdent("tc"),
+		Sel: ast.NewIdent(panicFieldName),
+	}
+	check := &ast.IfStmt{
+		Init: &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent("r")},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent("recover")}},
+		},
+		Cond: &ast.BinaryExpr{
+			X: &ast.ParenExpr{X: &ast.BinaryExpr{
+				X:  ast.NewIdent("r"),
+				Op: token.NEQ,
+				Y:  ast.NewIdent("nil"),
+			}},
+			Op: token.NEQ,
+			Y:  want,
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ExprStmt{
+					X: &ast.CallExpr{
+						Fun: &ast.SelectorExpr{
+							X:   ast.NewIdent(testingT),
+							Sel: ast.NewIdent("Errorf"),
+						},
+						Args: []ast.Expr{
+							&ast.BasicLit{
+								Kind:  token.STRING,
+								Value: "\"recovered %v, want panic: %t\"",
+							},
+							ast.NewIdent("r"),
+							want,
+						},
+					},
+				},
+			},
+		},
+	}
+	return &ast.DeferStmt{
+		Call: &ast.CallExpr{
+			Fun: &ast.FuncLit{
+				Type: &ast.FuncType{Params: &ast.FieldList{}},
+				Body: &ast.BlockStmt{List: []ast.Stmt{check}},
+			},
+		},
+	}
+}
+
+// refersToPackage reports whether f has a selector expression whose receiver is name.
+func refersToPackage(f *ast.File, name string) bool {
+	found := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == name {
+				found = true
 			}
 		}
+		return !found
+	})
+	return found
+}
+
+func (r *ExecuteResult) insertAuxiliaryFuncs(f *ast.File) {
+	insertFuncs := make(map[string]*ast.FuncDecl)
+
+	for i, ty := range r.SymbolTypes {
+		for _, rr := range r.RunResults {
+			addAuxiliaryFuncs(insertFuncs, rr.symbolValues[i], ty)
+		}
 	}
 
 	insertPos := len(f.Decls)
@@ -157,6 +411,56 @@ func (r *ExecuteResult) insertAuxiliaryFuncs(f *ast.File) {
 	f.Decls = append(f.Decls[:insertPos], append(newDecls, f.Decls[insertPos:]...)...)
 }
 
+// addAuxiliaryFuncs adds auxiliary functions required to
+// construct the value v of type ty to insertFuncs.
+func addAuxiliaryFuncs(insertFuncs map[string]*ast.FuncDecl, v interface{}, ty types.Type) {
+	switch ty := ty.(type) {
+	case *types.Pointer:
+		p := reflect.ValueOf(v)
+		if !p.IsValid() || p.IsNil() {
+			return
+		}
+		if elemTy, ok := ty.Elem().(*types.Basic); ok {
+			name := elemTy.Name() + "ptr"
+			if _, ok := insertFuncs[name]; !ok {
+				insertFuncs[name] = getAuxiliaryPtrFunc(name, elemTy)
+			}
+			return
+		}
+		addAuxiliaryFuncs(insertFuncs, p.Elem().Interface(), ty.Elem())
+	case *types.Named:
+		addAuxiliaryFuncs(insertFuncs, v, ty.Underlying())
+	case *types.Struct:
+		fields := reflect.ValueOf(v)
+		for i := 0; i < ty.NumFields(); i++ {
+			addAuxiliaryFuncs(insertFuncs, fields.Index(i).Interface(), ty.Field(i).Type())
+		}
+	case *types.Slice:
+		elems := reflect.ValueOf(v)
+		for i := 0; i < elems.Len(); i++ {
+			addAuxiliaryFuncs(insertFuncs, elems.Index(i).Interface(), ty.Elem())
+		}
+	case *types.Array:
+		elems := reflect.ValueOf(v)
+		for i := 0; i < elems.Len(); i++ {
+			addAuxiliaryFuncs(insertFuncs, elems.Index(i).Interface(), ty.Elem())
+		}
+	case *types.Interface:
+		if d, ok := v.(interp.SymbolicValue); ok {
+			addAuxiliaryFuncs(insertFuncs, d.Value, d.Type)
+		}
+	case *types.Map:
+		entries := reflect.ValueOf(v)
+		if entries.Kind() != reflect.Map {
+			return
+		}
+		for _, k := range entries.MapKeys() {
+			addAuxiliaryFuncs(insertFuncs, k.Interface(), ty.Key())
+			addAuxiliaryFuncs(insertFuncs, entries.MapIndex(k).Interface(), ty.Elem())
+		}
+	}
+}
+
 func getAuxiliaryPtrFunc(name string, ty *types.Basic) *ast.FuncDecl {
 	return &ast.FuncDecl{
 		Name: ast.NewIdent(name),
@@ -232,9 +536,15 @@ func (r *ExecuteResult) rewriteSymbols(runnerFunc *ast.FuncDecl) ([]string, []st
 		var name string
 		switch ty {
 		case symbolType:
-			if callExpr, ok := c.Parent().(*ast.CallExpr); ok {
-				sig := r.runnerTypesInfo.TypeOf(callExpr.Fun).(*types.Signature)
+			switch parent := c.Parent().(type) {
+			case *ast.CallExpr:
+				sig := r.runnerTypesInfo.TypeOf(parent.Fun).(*types.Signature)
 				symbolNames[i] = sig.Params().At(c.Index()).Name()
+			case *ast.SelectorExpr:
+				// The symbol is the receiver of the target method.
+				if n := r.targetFuncSig.Recv().Name(); n != "" && n != "_" {
+					symbolNames[i] = n
+				}
 			}
 			name = symbolNames[i]
 		case retValType:
@@ -0,0 +1,149 @@
+package congo
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const inPackageTestPackage = "github.com/ajalab/congo/testdata/inpkg"
+
+// importerFunc is a types.Importer implemented by a function.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// checkInPackageRunner type-checks the runner file src together with the files of targetPackage,
+// as the loader does with the overlay in the in-package mode.
+func checkInPackageRunner(t *testing.T, targetPackage *packages.Package, src []byte) (*ast.File, *types.Package, *types.Package, *types.Info) {
+	t.Helper()
+	fset := token.NewFileSet()
+	symbolFile, err := parser.ParseFile(fset, filepath.Join("symbol", "symbol.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbolPackage, err := (&types.Config{}).Check(congoSymbolPackagePath, fset, []*ast.File{symbolFile}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runnerFile, err := parser.ParseFile(fset, inPackageRunnerFileName, src, 0)
+	if err != nil {
+		t.Fatalf("the runner does not parse: %v\n%s", err, src)
+	}
+	files := []*ast.File{runnerFile}
+	for _, fpath := range targetPackage.GoFiles {
+		f, err := parser.ParseFile(fset, fpath, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	config := &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == congoSymbolPackagePath {
+				return symbolPackage, nil
+			}
+			// runtime is imported only for its side effects.
+			pkg := types.NewPackage(path, filepath.Base(path))
+			pkg.MarkComplete()
+			return pkg, nil
+		}),
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	pkg, err := config.Check(targetPackage.PkgPath, fset, files, info)
+	if err != nil {
+		t.Fatalf("the runner does not type-check in the target package: %v\n%s", err, src)
+	}
+	return runnerFile, pkg, symbolPackage, info
+}
+
+func TestGenerateInPackage(t *testing.T) {
+	targetPackage, err := loadTargetPackage(inPackageTestPackage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets, err := loadTargetFuncs(inPackageTestPackage, targetPackage, nil, &ExecuteOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := targets["atLeast"]; !ok || len(targets) != 1 {
+		t.Fatalf("expected the unexported target atLeast, actual %v", targets)
+	}
+
+	fpath, src, err := generateInPackageRunner(targetPackage, targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir := filepath.Dir(targetPackage.GoFiles[0]); fpath != filepath.Join(dir, "zz_congo_runner.go") {
+		t.Errorf("the runner should be placed in %s, actual %s", dir, fpath)
+	}
+	runnerFile, pkg, symbolPackage, info := checkInPackageRunner(t, targetPackage, src)
+	if runnerFile.Name.Name != "inpkg" {
+		t.Errorf("the runner should belong to package inpkg, actual %s", runnerFile.Name.Name)
+	}
+	if runnerFile.Scope.Lookup("main") != nil {
+		t.Error("the in-package runner should not have the main function")
+	}
+	runnerName := targets["atLeast"].runnerName
+	if runnerName != runnerFuncNamePrefix+"atLeast" || runnerFile.Scope.Lookup(runnerName) == nil {
+		t.Errorf("the runner function %q is not declared", runnerName)
+	}
+	if !bytes.Contains(src, []byte("atLeast(symbol.Symbols[0].(counter), symbol.Symbols[1].(int))")) {
+		t.Errorf("the runner should call atLeast without the qualifier:\n%s", src)
+	}
+
+	targetFunc := pkg.Scope().Lookup("atLeast").(*types.Func)
+	sig := targetFunc.Type().(*types.Signature)
+	r := &ExecuteResult{
+		SymbolTypes: []types.Type{sig.Params().At(0).Type(), sig.Params().At(1).Type()},
+		RunResults: []*RunResult{
+			{symbolValues: []interface{}{[]interface{}{3}, 1}, returnValues: 3},
+			{symbolValues: []interface{}{[]interface{}{3}, 5}, returnValues: 5},
+		},
+		inPackage:          true,
+		runnerFile:         runnerFile,
+		runnerTypesInfo:    info,
+		runnerPackage:      pkg,
+		runnerFuncName:     runnerName,
+		targetPackage:      pkg,
+		congoSymbolPackage: symbolPackage,
+		targetFuncSig:      sig,
+		targetFuncName:     "atLeast",
+	}
+	f, err := r.GenerateTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), f); err != nil {
+		t.Fatal(err)
+	}
+	code := buf.String()
+	for _, want := range []string{
+		"package inpkg\n",
+		"func TestAtLeast(t *testing.T) {",
+		"c        counter",
+		"{counter{n: 3}, 5, 5}",
+		"actual0 := atLeast(tc.c, tc.x)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("the test should contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "inpkg.") || strings.Contains(code, inPackageTestPackage) {
+		t.Errorf("the test should refer to the target package without the qualifier:\n%s", code)
+	}
+}
@@ -1,13 +1,17 @@
 package interp
 
 import (
+	"fmt"
 	"go/types"
+	"sync/atomic"
 
 	"golang.org/x/tools/go/ssa"
 )
 
 const congoSymbolPackagePath = "github.com/ajalab/congo/symbol"
 
+// SymbolicValue is a value of a symbol together with its type.
+// It also represents a non-nil value of an interface type as a pair of the dynamic value and type.
 type SymbolicValue struct {
 	Value interface{}
 	Type  types.Type
@@ -21,9 +25,42 @@ func value2InterpValue(v interface{}, t types.Type) value {
 		vs := v.([]interface{})
 		values := make(structure, len(vs))
 		for i, v := range vs {
-			values[i] = v.(value)
+			values[i] = value2InterpValue(v, t.Field(i).Type())
 		}
 		return values
+	case *types.Slice:
+		vs := v.([]interface{})
+		if vs == nil {
+			return []value(nil)
+		}
+		values := make([]value, len(vs))
+		for i, v := range vs {
+			values[i] = value2InterpValue(v, t.Elem())
+		}
+		return values
+	case *types.Array:
+		vs := v.([]interface{})
+		values := make(array, len(vs))
+		for i, v := range vs {
+			values[i] = value2InterpValue(v, t.Elem())
+		}
+		return values
+	case *types.Map:
+		vs := v.(map[interface{}]interface{})
+		if vs == nil {
+			return zero(t)
+		}
+		m := makeMap(t.Key(), len(vs))
+		for k, v := range vs {
+			k, v := value2InterpValue(k, t.Key()), value2InterpValue(v, t.Elem())
+			switch m := m.(type) {
+			case map[value]value:
+				m[k] = v
+			case *hashmap:
+				m.insert(k.(hashable), v)
+			}
+		}
+		return m
 	case *types.Named:
 		return value2InterpValue(v, t.Underlying())
 	case *types.Pointer:
@@ -33,6 +70,15 @@ func value2InterpValue(v interface{}, t types.Type) value {
 		}
 		val := value2InterpValue(*a, t.Elem())
 		return &val
+	case *types.Interface:
+		d, ok := v.(SymbolicValue)
+		if !ok {
+			return iface{}
+		}
+		return iface{
+			t: d.Type,
+			v: value2InterpValue(d.Value, d.Type),
+		}
 	}
 	return nil
 }
@@ -42,4 +88,49 @@ type CongoInterpResult struct {
 	ExitCode int
 	Instrs   []ssa.Instruction
 	Return   interface{}
+	// BudgetExceeded reports whether the interpretation was aborted because it exceeded the limits.
+	BudgetExceeded bool
+}
+
+// Limits bounds the interpretation so that a program that does not terminate
+// (or runs for too long) does not hang the caller. A zero field imposes no limit.
+type Limits struct {
+	// MaxSteps is the maximum number of instructions to execute.
+	MaxSteps uint
+	// MaxTrace is the maximum number of instructions recorded in the trace.
+	MaxTrace uint
+}
+
+// budgetPanic is the type of a panic that aborts the interpretation
+// when it exceeds the limits given to Interpret.
+type budgetPanic string
+
+// step counts an executed instruction and aborts the interpretation if it exceeds the step limit.
+func (i *interpreter) step() {
+	if max := i.congoLimits.MaxSteps; max > 0 && atomic.AddUint64(&i.congoSteps, 1) > uint64(max) {
+		panic(budgetPanic(fmt.Sprintf("more than %d steps", max)))
+	}
+}
+
+// trace appends instr to the trace and aborts the interpretation if the trace exceeds the limit.
+func (i *interpreter) trace(instr ssa.Instruction) {
+	if max := i.congoLimits.MaxTrace; max > 0 && uint(len(i.congoTraceInstrs)) >= max {
+		panic(budgetPanic(fmt.Sprintf("more than %d traced instructions", max)))
+	}
+	i.congoTraceInstrs = append(i.congoTraceInstrs, instr)
+}
+
+// interruptPanic is the type of a panic that aborts the interpretation
+// when the context given to Interpret is done.
+type interruptPanic struct {
+	err error
+}
+
+// checkInterrupt aborts the interpretation if the context is done.
+func (i *interpreter) checkInterrupt() {
+	select {
+	case <-i.congoContext.Done():
+		panic(interruptPanic{i.congoContext.Err()})
+	default:
+	}
 }
@@ -47,6 +47,7 @@
 package interp
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"go/token"
@@ -93,6 +94,9 @@ type interpreter struct {
 	congoTraceTarget *ssa.Function
 	congoTraceInstrs []ssa.Instruction
 	congoReturnValue interface{}
+	congoContext     context.Context
+	congoLimits      Limits
+	congoSteps       uint64 // atomically updated
 	// TODO(ajalab) Use mutex to update congoTrace?
 	// congoMutex sync.Mutex
 }
@@ -583,10 +587,12 @@ func runFrame(fr *frame) {
 		if fr.i.mode&EnableTracing != 0 {
 			fmt.Fprintf(os.Stderr, ".%s:\n", fr.block)
 		}
+		fr.i.checkInterrupt()
 	block:
 		for _, instr := range fr.block.Instrs {
+			fr.i.step()
 			if tracing {
-				fr.i.congoTraceInstrs = append(fr.i.congoTraceInstrs, instr)
+				fr.i.trace(instr)
 			}
 			if fr.i.mode&EnableTracing != 0 {
 				if v, ok := instr.(ssa.Value); ok {
@@ -622,6 +628,9 @@ func doRecover(caller *frame) value {
 
 		// TODO(adonovan): support runtime.Goexit.
 		switch p := p.(type) {
+		case interruptPanic, budgetPanic:
+			// The interpretation is being aborted.
+			panic(p)
 		case targetPanic:
 			// The target program explicitly called panic().
 			return p.v
@@ -675,13 +684,15 @@ func deleteBodies(pkg *ssa.Package, except ...string) {
 // mode specifies various interpreter options.  filename and args are
 // the initial values of os.Args for the target program.  sizes is the
 // effective type-sizing function for this program.
+// The interpretation is aborted with the error of ctx when ctx is done,
+// or when it exceeds limits, in which case the result reports BudgetExceeded.
 //
 // Interpret returns the exit code of the program: 2 for panic (like
 // gc does), or the argument to os.Exit for normal termination.
 //
 // The SSA program must include the "runtime" package.
 //
-func Interpret(mainpkg *ssa.Package, targetfunc *ssa.Function, runnerName string, symbolicValues []SymbolicValue, mode Mode, sizes types.Sizes, filename string, args []string) (result *CongoInterpResult, err error) {
+func Interpret(ctx context.Context, mainpkg *ssa.Package, targetfunc *ssa.Function, runnerName string, symbolicValues []SymbolicValue, limits Limits, mode Mode, sizes types.Sizes, filename string, args []string) (result *CongoInterpResult, err error) {
 	if syswrite == nil {
 		panic("Interpret: unsupported platform.")
 	}
@@ -695,6 +706,8 @@ func Interpret(mainpkg *ssa.Package, targetfunc *ssa.Function, runnerName string
 
 		congoTraceTarget: targetfunc,
 		congoTraceInstrs: make([]ssa.Instruction, 0),
+		congoContext:     ctx,
+		congoLimits:      limits,
 	}
 
 	runtimePkg := i.prog.ImportedPackage("runtime")
@@ -737,6 +750,11 @@ func Interpret(mainpkg *ssa.Package, targetfunc *ssa.Function, runnerName string
 			values := make([]value, len(symbolicValues))
 			for i := 0; i < len(symbolicValues); i++ {
 				t := symbolicValues[i].Type
+				if types.IsInterface(t) {
+					// The symbol holds the dynamic type and value.
+					values[i] = value2InterpValue(symbolicValues[i].Value, t)
+					continue
+				}
 				values[i] = iface{
 					t: t,
 					v: value2InterpValue(symbolicValues[i].Value, t),
@@ -758,11 +776,17 @@ func Interpret(mainpkg *ssa.Package, targetfunc *ssa.Function, runnerName string
 
 	// Top-level error handler.
 	exitCode := 2
+	budgetExceeded := false
 	defer func() {
 		switch p := recover().(type) {
 		case nil:
 		case exitPanic:
 			exitCode = int(p)
+		case interruptPanic:
+			err = p.err
+		case budgetPanic:
+			budgetExceeded = true
+			err = errors.New("budget exceeded: " + string(p))
 		case targetPanic:
 			err = errors.New("panic: " + toString(p.v))
 		case runtime.Error:
@@ -776,6 +800,8 @@ func Interpret(mainpkg *ssa.Package, targetfunc *ssa.Function, runnerName string
 			ExitCode: exitCode,
 			Instrs:   i.congoTraceInstrs,
 			Return:   i.congoReturnValue,
+
+			BudgetExceeded: budgetExceeded,
 		}
 
 		// TODO(adonovan): dump panicking interpreter goroutine?
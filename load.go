@@ -4,13 +4,18 @@ import (
 	"go/ast"
 	"go/constant"
 	"go/token"
+	"go/types"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
+	"github.com/ajalab/congo/log"
+	"github.com/ajalab/congo/solver"
 	"golang.org/x/tools/go/packages"
 
 	"golang.org/x/tools/go/ssa"
@@ -52,6 +57,10 @@ type Config struct {
 	// Runner is the path to the Go file that calls the target function.
 	// Automatically generated if empty string is specified.
 	Runner string
+	// InPackage makes the runner and the generated tests belong to the target package
+	// instead of the external test package (*_test).
+	// Unexported functions can be targeted in this mode.
+	InPackage bool
 	ExecuteOption
 }
 
@@ -108,6 +117,27 @@ func parseAnnotation(text string, eo *ExecuteOption) (bool, error) {
 					return false, err
 				}
 				reflect.ValueOf(eo).Elem().Field(i).SetFloat(fv)
+			case reflect.String:
+				reflect.ValueOf(eo).Elem().Field(i).SetString(value)
+			case reflect.Bool:
+				// A directive without a value enables the option.
+				bv := true
+				if value != "" {
+					var err error
+					if bv, err = strconv.ParseBool(value); err != nil {
+						return false, err
+					}
+				}
+				reflect.ValueOf(eo).Elem().Field(i).SetBool(bv)
+			case reflect.Int64:
+				if f.Type != reflect.TypeOf(time.Duration(0)) {
+					return false, errors.Errorf("unsupported option type: %s", f.Type)
+				}
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return false, err
+				}
+				reflect.ValueOf(eo).Elem().Field(i).SetInt(int64(d))
 			default:
 				return false, errors.Errorf("unsupported option tyupe: %s", f.Type)
 			}
@@ -136,6 +166,73 @@ func getExecuteOption(funcDecl *ast.FuncDecl, cgroups []*ast.CommentGroup) (*Exe
 	return nil, nil
 }
 
+// normalizeFuncName normalizes the name of a target function.
+// Methods are specified in the form of "T.M", "*T.M", or "(*T).M",
+// which are normalized into "T.M".
+func normalizeFuncName(name string) string {
+	recvName, funcName := splitFuncName(name)
+	if recvName == "" {
+		return funcName
+	}
+	return recvName + "." + funcName
+}
+
+// splitFuncName splits the name of a target function into the receiver type name and the function name.
+// The receiver type name is empty if name is not a method.
+func splitFuncName(name string) (string, string) {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return "", name
+	}
+	recvName := strings.TrimLeft(strings.Trim(name[:i], "()"), "*")
+	return recvName, name[i+1:]
+}
+
+// funcDeclName returns the name of the function declared by funcDecl.
+// The name of a method M of type T is "T.M".
+func funcDeclName(funcDecl *ast.FuncDecl) string {
+	name := funcDecl.Name.Name
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return name
+	}
+	recvType := funcDecl.Recv.List[0].Type
+	for {
+		switch ty := recvType.(type) {
+		case *ast.StarExpr:
+			recvType = ty.X
+		case *ast.ParenExpr:
+			recvType = ty.X
+		case *ast.Ident:
+			return ty.Name + "." + name
+		default:
+			return name
+		}
+	}
+}
+
+// lookupTargetFunc returns the function or the method that has the given name in pkg.
+func lookupTargetFunc(pkg *types.Package, name string) (*types.Func, error) {
+	recvName, funcName := splitFuncName(name)
+	if recvName == "" {
+		f, ok := pkg.Scope().Lookup(funcName).(*types.Func)
+		if !ok {
+			return nil, errors.Errorf("function %s does not exist in package %s", funcName, pkg.Path())
+		}
+		return f, nil
+	}
+	typeName, ok := pkg.Scope().Lookup(recvName).(*types.TypeName)
+	if !ok {
+		return nil, errors.Errorf("type %s does not exist in package %s", recvName, pkg.Path())
+	}
+	// Methods of both T and *T are found in the method set of *T.
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(typeName.Type()), false, pkg, funcName)
+	f, ok := obj.(*types.Func)
+	if !ok {
+		return nil, errors.Errorf("method %s does not exist for type %s in package %s", funcName, recvName, pkg.Path())
+	}
+	return f, nil
+}
+
 func loadTargetFuncs(
 	targetPackagePath string,
 	targetPackage *packages.Package,
@@ -177,15 +274,16 @@ func loadTargetFuncs(
 		// case 2 or 4
 	FUNC:
 		for _, name := range funcNames {
+			name = normalizeFuncName(name)
 			for j, f := range fs {
-				obj := f.Scope.Lookup(name)
-				if obj == nil {
-					continue
-				}
-				if funcDecl, ok := obj.Decl.(*ast.FuncDecl); ok {
+				for _, decl := range f.Decls {
+					funcDecl, ok := decl.(*ast.FuncDecl)
+					if !ok || funcDeclName(funcDecl) != name {
+						continue
+					}
 					eo, err := getExecuteOption(funcDecl, cmaps[j][funcDecl])
 					if err != nil {
-						return nil, errors.Wrapf(err, "failed to parse annotations for function %s", funcDecl.Name)
+						return nil, errors.Wrapf(err, "failed to parse annotations for function %s", name)
 					}
 					if eo == nil {
 						eo = &ExecuteOption{}
@@ -204,10 +302,10 @@ func loadTargetFuncs(
 	for i, f := range fs {
 		for _, decl := range f.Decls {
 			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-				name := funcDecl.Name.String()
+				name := funcDeclName(funcDecl)
 				eo, err := getExecuteOption(funcDecl, cmaps[i][funcDecl])
 				if err != nil {
-					return nil, errors.Wrapf(err, "failed to parse annotations for function %s", funcDecl.Name)
+					return nil, errors.Wrapf(err, "failed to parse annotations for function %s", name)
 				}
 				if eo == nil {
 					continue
@@ -246,26 +344,62 @@ func Load(config *Config, targetPackagePath string) (*Congo, error) {
 		return nil, errors.Errorf("no target functions could be found in %s", targetPackage.PkgPath)
 	}
 
-	// Generate a runner file if config.Runner is nil.
-	runnerPackageFPath := config.Runner
-	if runnerPackageFPath == "" {
-		runnerPackageFPath, err = generateRunner(targetPackage, targets)
+	if config.Runner != "" {
+		return nil, errors.New("user-specified runner is not supported yet")
+	}
+	inPackage := config.InPackage
+	if !inPackage {
+		name, field, err := unexportedParamField(targetPackage.Types, targets)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to generate a runner")
+			return nil, err
+		}
+		if field != nil {
+			// Tests in the external test package cannot set the field.
+			log.Info.Printf("%s takes a value with the unexported field %s, so the tests are generated in the in-package mode", name, field.Name())
+			inPackage = true
+		}
+	}
+	if !inPackage {
+		for name := range targets {
+			recvName, funcName := splitFuncName(name)
+			if !ast.IsExported(funcName) || (recvName != "" && !ast.IsExported(recvName)) {
+				return nil, errors.Errorf("%s is unexported and can be tested only in the in-package mode", name)
+			}
 		}
-		defer os.Remove(runnerPackageFPath)
-	} else {
-		return nil, errors.New("user-specified runner is not supported yet")
 	}
 
 	// IPath represents an import path.
 	targetPackageIPath := targetPackage.PkgPath
-	return load(targets, targetPackageIPath, runnerPackageFPath)
+
+	// Generate a runner file in the target package and load it as an overlay.
+	if inPackage {
+		runnerPackageFPath, runnerSrc, err := generateInPackageRunner(targetPackage, targets)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to generate a runner")
+		}
+		overlay := map[string][]byte{runnerPackageFPath: runnerSrc}
+		return load(targets, targetPackageIPath, targetPackageIPath, runnerPackageFPath, overlay)
+	}
+
+	// Generate a runner file in a separate main package.
+	runnerPackageFPath, err := generateRunner(targetPackage, targets)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate a runner")
+	}
+	defer os.Remove(runnerPackageFPath)
+	return load(targets, targetPackageIPath, runnerPackageFPath, runnerPackageFPath, nil)
 }
 
-func load(targets map[string]*Target, targetPackageIPath, runnerPackagePath string) (*Congo, error) {
+// load loads the runner package specified by runnerPackagePath and the runner file runnerFPath in it.
+// overlay is given to the loader if the runner file does not exist on disk.
+func load(
+	targets map[string]*Target,
+	targetPackageIPath, runnerPackagePath, runnerFPath string,
+	overlay map[string][]byte,
+) (*Congo, error) {
 	pConfig := &packages.Config{
-		Mode: packages.LoadAllSyntax,
+		Mode:    packages.LoadAllSyntax,
+		Overlay: overlay,
 	}
 	pkgs, err := packages.Load(pConfig, runnerPackagePath)
 	if err != nil {
@@ -294,6 +428,17 @@ func load(targets map[string]*Target, targetPackageIPath, runnerPackagePath stri
 
 	runnerPackage := pkgs[runnerPackageIdx]
 	targetPackage := runnerPackage.Imports[targetPackageIPath]
+	inPackage := runnerPackage.PkgPath == targetPackageIPath
+	if inPackage {
+		targetPackage = runnerPackage
+	}
+	runnerFile := runnerPackage.Syntax[0]
+	for i, fpath := range runnerPackage.CompiledGoFiles {
+		if fpath == runnerFPath {
+			runnerFile = runnerPackage.Syntax[i]
+			break
+		}
+	}
 	congoSymbolPackage := runnerPackage.Imports[congoSymbolPackagePath]
 
 	ssaProg, ssaPkgs := ssautil.AllPackages(pkgs, ssa.BuilderMode(0))
@@ -358,12 +503,21 @@ func load(targets map[string]*Target, targetPackageIPath, runnerPackagePath stri
 			symbols[subst.i] = subst.v
 		}
 
-		target.f = targetPackageSSA.Func(target.name)
+		targetFunc, err := lookupTargetFunc(targetPackage.Types, target.name)
+		if err != nil {
+			return nil, err
+		}
+		target.f = ssaProg.FuncValue(targetFunc)
 		target.symbols = symbols
+		if err := solver.CheckSymbols(target.Solver, symbols); err != nil {
+			return nil, errors.Wrapf(err, "failed to load %s", target.name)
+		}
 	}
 
 	program := &Program{
-		runnerFile:         runnerPackage.Syntax[0],
+		inPackage:          inPackage,
+		concreteTypes:      concreteTypes(ssaProg, targetPackageSSA, inPackage),
+		runnerFile:         runnerFile,
 		runnerTypesInfo:    runnerPackage.TypesInfo,
 		runnerPackage:      runnerPackageSSA,
 		targetPackage:      targetPackageSSA,
@@ -375,3 +529,165 @@ func load(targets map[string]*Target, targetPackageIPath, runnerPackagePath stri
 		targets: targets,
 	}, nil
 }
+
+// unexportedParamField returns a target function in pkg that has a parameter (or a receiver)
+// whose value contains unexported fields, all of which belong to pkg, and one of the fields.
+// It returns a nil field if no target function has such a parameter.
+func unexportedParamField(pkg *types.Package, targets map[string]*Target) (string, *types.Var, error) {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := lookupTargetFunc(pkg, name)
+		if err != nil {
+			return "", nil, err
+		}
+		sig := f.Type().(*types.Signature)
+		var params []*types.Var
+		if sig.Recv() != nil {
+			params = append(params, sig.Recv())
+		}
+		for i := 0; i < sig.Params().Len(); i++ {
+			params = append(params, sig.Params().At(i))
+		}
+		for _, param := range params {
+			field := unexportedField(param.Type(), nil, make(map[types.Type]bool))
+			if field != nil && unexportedField(param.Type(), pkg, make(map[types.Type]bool)) == nil {
+				return name, field, nil
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+// unexportedField returns an unexported field of a struct contained in values of ty
+// that cannot be referred to from package pkg, or nil if there is none.
+// pkg is nil if no unexported fields can be referred to.
+// The dynamic values of interfaces are not considered.
+func unexportedField(ty types.Type, pkg *types.Package, visited map[types.Type]bool) *types.Var {
+	if visited[ty] {
+		return nil
+	}
+	visited[ty] = true
+	switch ty := ty.(type) {
+	case *types.Named:
+		return unexportedField(ty.Underlying(), pkg, visited)
+	case *types.Pointer:
+		return unexportedField(ty.Elem(), pkg, visited)
+	case *types.Slice:
+		return unexportedField(ty.Elem(), pkg, visited)
+	case *types.Array:
+		return unexportedField(ty.Elem(), pkg, visited)
+	case *types.Map:
+		if field := unexportedField(ty.Key(), pkg, visited); field != nil {
+			return field
+		}
+		return unexportedField(ty.Elem(), pkg, visited)
+	case *types.Struct:
+		for i := 0; i < ty.NumFields(); i++ {
+			field := ty.Field(i)
+			if !field.Exported() && field.Pkg() != pkg {
+				return field
+			}
+			if field := unexportedField(field.Type(), pkg, visited); field != nil {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// concreteTypes returns the types that can be dynamic types of symbolic interface values:
+// the named types T and *T in the target package, followed by the runtime types of the program in other packages
+// (e.g., *io.LimitedReader converted to io.Reader somewhere in the program).
+// Unexported types are included only in the in-package mode since generated tests cannot refer to them otherwise.
+func concreteTypes(prog *ssa.Program, pkg *ssa.Package, inPackage bool) []types.Type {
+	var accessible *types.Package
+	if inPackage {
+		accessible = pkg.Pkg
+	}
+
+	names := make([]string, 0, len(pkg.Members))
+	for name, member := range pkg.Members {
+		if _, ok := member.(*ssa.Type); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var tys []types.Type
+	for _, name := range names {
+		obj := pkg.Members[name].(*ssa.Type).Object()
+		if !obj.Exported() && !inPackage {
+			continue
+		}
+		ty := obj.Type()
+		if !isConcreteType(ty, accessible) {
+			continue
+		}
+		tys = append(tys, ty)
+		if _, ok := ty.Underlying().(*types.Struct); ok {
+			tys = append(tys, types.NewPointer(ty))
+		}
+	}
+
+	// RuntimeTypes is unordered and may have identical pointer types, so they are sorted by their names.
+	others := make(map[string]types.Type)
+	for _, ty := range prog.RuntimeTypes() {
+		named, _ := ty.(*types.Named)
+		if ptr, ok := ty.(*types.Pointer); ok {
+			named, _ = ptr.Elem().(*types.Named)
+		}
+		if named == nil || named.Obj().Pkg() == nil || named.Obj().Pkg() == pkg.Pkg {
+			continue
+		}
+		if !named.Obj().Exported() || !isImportable(named.Obj().Pkg().Path()) || !isConcreteType(ty, nil) {
+			continue
+		}
+		others[types.TypeString(ty, nil)] = ty
+	}
+	otherNames := make([]string, 0, len(others))
+	for name := range others {
+		otherNames = append(otherNames, name)
+	}
+	sort.Strings(otherNames)
+	for _, name := range otherNames {
+		tys = append(tys, others[name])
+	}
+	return tys
+}
+
+// isConcreteType reports whether ty, which is either a named type T or a pointer *T to a named struct type,
+// can be a dynamic type of symbolic interface values.
+// Generated tests express the values with composite literals, so the unexported fields
+// in them must belong to pkg, which is nil if no unexported fields can be referred to.
+func isConcreteType(ty types.Type, pkg *types.Package) bool {
+	if ptr, ok := ty.(*types.Pointer); ok {
+		if _, ok := ptr.Elem().Underlying().(*types.Struct); !ok {
+			return false
+		}
+		ty = ptr.Elem()
+	}
+	switch underlying := ty.Underlying().(type) {
+	case *types.Basic:
+		return underlying.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) > 0
+	case *types.Struct, *types.Slice, *types.Array, *types.Map:
+		return unexportedField(ty, pkg, make(map[types.Type]bool)) == nil
+	}
+	return false
+}
+
+// isImportable reports whether generated tests can import the package of the import path.
+func isImportable(path string) bool {
+	if path == "main" {
+		return false
+	}
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "internal" || elem == "vendor" {
+			return false
+		}
+	}
+	return true
+}
@@ -2,7 +2,19 @@ package congo
 
 import (
 	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/ajalab/congo/interp"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
 )
 
 func strSetEqual(xs, ys []string) bool {
@@ -45,11 +57,44 @@ func TestLoadTargetPackage(t *testing.T) {
 	}
 }
 
+// overrideExecuteOption returns a copy of eo modified by override.
+func overrideExecuteOption(eo *ExecuteOption, override func(eo *ExecuteOption)) *ExecuteOption {
+	copied := *eo
+	override(&copied)
+	return &copied
+}
+
 func TestLoadTargetFuncs(t *testing.T) {
 	zeroExecuteOption := &ExecuteOption{}
 	myExecuteOption := &ExecuteOption{MaxExec: 100}
-	fooExecuteOption := &ExecuteOption{MaxExec: 10, MinCoverage: 0.75}
-	barExecuteOption := &ExecuteOption{MaxExec: 50, MinCoverage: defaultExecuteOption.MinCoverage}
+	fooExecuteOption := overrideExecuteOption(defaultExecuteOption, func(eo *ExecuteOption) {
+		eo.MaxExec = 10
+		eo.MinCoverage = 0.75
+	})
+	barExecuteOption := overrideExecuteOption(defaultExecuteOption, func(eo *ExecuteOption) {
+		eo.MaxExec = 50
+		eo.Strategy = "bfs"
+		eo.QueryTimeout = 500 * time.Millisecond
+		eo.Timeout = time.Minute
+		eo.MaxSteps = 100000
+		eo.Metric = "edge"
+		eo.Solver = "smtlib"
+		eo.SolverCommand = "cvc5 --lang smt2 --incremental"
+		eo.Readable = true
+		eo.Overflow = true
+		eo.OverflowTests = true
+	})
+	methodExecuteOption := overrideExecuteOption(defaultExecuteOption, func(eo *ExecuteOption) {
+		eo.MaxExec = 20
+		eo.LoopBound = 3
+		eo.SummarizeLoops = true
+	})
+	// withMyMaxExec overrides MaxExec by that of myExecuteOption.
+	withMyMaxExec := func(eo *ExecuteOption) *ExecuteOption {
+		return overrideExecuteOption(eo, func(eo *ExecuteOption) {
+			eo.MaxExec = myExecuteOption.MaxExec
+		})
+	}
 	tcs := []struct {
 		packagePath string
 		funcNames   []string
@@ -60,16 +105,18 @@ func TestLoadTargetFuncs(t *testing.T) {
 			"testdata/load/foo.go",
 			nil,
 			zeroExecuteOption,
-			map[string]*ExecuteOption{"AnnotatedFoo": fooExecuteOption},
+			map[string]*ExecuteOption{
+				"AnnotatedFoo":        fooExecuteOption,
+				"Foo.AnnotatedMethod": methodExecuteOption,
+			},
 		},
 		{
 			"testdata/load/foo.go",
 			nil,
 			myExecuteOption,
 			map[string]*ExecuteOption{
-				"AnnotatedFoo": {
-					MaxExec: myExecuteOption.MaxExec, MinCoverage: fooExecuteOption.MinCoverage,
-				},
+				"AnnotatedFoo":        withMyMaxExec(fooExecuteOption),
+				"Foo.AnnotatedMethod": withMyMaxExec(methodExecuteOption),
 			},
 		},
 		{
@@ -86,12 +133,17 @@ func TestLoadTargetFuncs(t *testing.T) {
 			[]string{"AnnotatedFoo", "NonAnnotatedFoo"},
 			myExecuteOption,
 			map[string]*ExecuteOption{
-				"AnnotatedFoo": {
-					MaxExec: myExecuteOption.MaxExec, MinCoverage: fooExecuteOption.MinCoverage,
-				},
-				"NonAnnotatedFoo": {
-					MaxExec: myExecuteOption.MaxExec, MinCoverage: defaultExecuteOption.MinCoverage,
-				},
+				"AnnotatedFoo":    withMyMaxExec(fooExecuteOption),
+				"NonAnnotatedFoo": withMyMaxExec(defaultExecuteOption),
+			},
+		},
+		{
+			"testdata/load/foo.go",
+			[]string{"(*Foo).AnnotatedMethod", "Foo.NonAnnotatedMethod"},
+			zeroExecuteOption,
+			map[string]*ExecuteOption{
+				"Foo.AnnotatedMethod":    methodExecuteOption,
+				"Foo.NonAnnotatedMethod": defaultExecuteOption,
 			},
 		},
 		{
@@ -99,8 +151,9 @@ func TestLoadTargetFuncs(t *testing.T) {
 			nil,
 			zeroExecuteOption,
 			map[string]*ExecuteOption{
-				"AnnotatedFoo": fooExecuteOption,
-				"AnnotatedBar": barExecuteOption,
+				"AnnotatedFoo":        fooExecuteOption,
+				"AnnotatedBar":        barExecuteOption,
+				"Foo.AnnotatedMethod": methodExecuteOption,
 			},
 		},
 		{
@@ -138,7 +191,7 @@ func TestLoadTargetFuncs(t *testing.T) {
 				if !ok {
 					t.Fatalf("function \"%s\" is an unexpected target", k)
 				}
-				if a.MaxExec != e.MaxExec || a.MinCoverage != e.MinCoverage {
+				if !reflect.DeepEqual(a.ExecuteOption, e) {
 					t.Errorf("execute options are wrong for function %s: expected %+v, actual %+v", k, e, a.ExecuteOption)
 				}
 			}
@@ -175,3 +228,161 @@ func TestParseAnnotationDirective(t *testing.T) {
 		})
 	}
 }
+
+func TestUnexportedParamField(t *testing.T) {
+	src := `package p
+
+import "time"
+
+type Exported struct {
+	X int
+}
+
+type Counter struct {
+	Name  string
+	count int
+}
+
+func (c *Counter) Inc() {}
+
+func Plain(x int, e Exported) {}
+
+func Nested(cs []*Counter) {}
+
+func Foreign(t time.Time) {}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("p", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name  string
+		field string
+	}{
+		{"Plain", ""},
+		{"Nested", "count"},
+		{"Counter.Inc", "count"},
+		// The tests cannot set the unexported fields of time.Time in either mode.
+		{"Foreign", ""},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			targets := map[string]*Target{tc.name: &Target{name: tc.name}}
+			name, field, err := unexportedParamField(pkg, targets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.field == "" {
+				if field != nil {
+					t.Errorf("%s should not have unexported fields, but %s has %s", tc.name, name, field.Name())
+				}
+				return
+			}
+			if field == nil || field.Name() != tc.field || name != tc.name {
+				t.Errorf("expected %s to have the unexported field %s, actual %s %v", tc.name, tc.field, name, field)
+			}
+		})
+	}
+}
+
+func TestConcreteTypes(t *testing.T) {
+	src := `package p
+
+import (
+	"io"
+	"strings"
+)
+
+type Buffer struct {
+	Data []byte
+}
+
+func (b *Buffer) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+type counter struct {
+	n int
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func Readers() []io.Reader {
+	return []io.Reader{
+		&io.LimitedReader{},
+		// *strings.Reader has unexported fields, which generated tests cannot set.
+		strings.NewReader(""),
+	}
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, _, err := ssautil.BuildPackage(conf, fset, types.NewPackage("p", "p"), []*ast.File{f}, ssa.BuilderMode(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerType := pkg.Prog.ImportedPackage("io").Pkg.Scope().Lookup("Reader").Type()
+	reader := readerType.Underlying().(*types.Interface)
+
+	tcs := []struct {
+		inPackage bool
+		expected  []string
+	}{
+		{false, []string{"p.Buffer", "*p.Buffer", "*io.LimitedReader"}},
+		{true, []string{"p.Buffer", "*p.Buffer", "p.counter", "*p.counter", "*io.LimitedReader"}},
+	}
+	for _, tc := range tcs {
+		t.Run(fmt.Sprintf("inPackage=%t", tc.inPackage), func(t *testing.T) {
+			tys := concreteTypes(pkg.Prog, pkg, tc.inPackage)
+			actual := make([]string, len(tys))
+			for i, ty := range tys {
+				actual[i] = types.TypeString(ty, nil)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("concrete types are wrong: expected %v, actual %v", tc.expected, actual)
+			}
+
+			// The implementations of io.Reader are found in both the target package and io.
+			var impls []string
+			for _, ty := range tys {
+				if types.Implements(ty, reader) {
+					impls = append(impls, types.TypeString(ty, nil))
+				}
+			}
+			if len(impls) == 0 || impls[len(impls)-1] != "*io.LimitedReader" {
+				t.Errorf("*io.LimitedReader should implement io.Reader: %v", impls)
+			}
+		})
+	}
+
+	// The generated tests import io to refer to the dynamic type.
+	tys := concreteTypes(pkg.Prog, pkg, false)
+	var limited interface{} = []interface{}{nil, int64(0)}
+	r := &ExecuteResult{
+		SymbolTypes: []types.Type{readerType},
+		RunResults: []*RunResult{{
+			symbolValues: []interface{}{interp.SymbolicValue{Value: &limited, Type: tys[len(tys)-1]}},
+		}},
+		targetFuncSig: types.NewSignature(nil, nil, nil, false),
+	}
+	var paths []string
+	for _, pkg := range r.referredPackages() {
+		paths = append(paths, pkg.Path())
+	}
+	if !reflect.DeepEqual(paths, []string{"io"}) {
+		t.Errorf("referred packages are wrong: expected [io], actual %v", paths)
+	}
+}
@@ -0,0 +1,268 @@
+package congo
+
+import (
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"github.com/ajalab/congo/solver"
+	"golang.org/x/tools/go/ssa"
+)
+
+// loop is a natural loop in a function.
+type loop struct {
+	header *ssa.BasicBlock
+	body   map[*ssa.BasicBlock]bool
+	// counting is the summary of the loop if it is a simple counting loop (see solver.CountingLoop).
+	counting *solver.CountingLoop
+}
+
+// loops finds the loops of functions and the innermost loop of each block.
+// The loops of a function are computed on demand.
+type loops struct {
+	innermost map[*ssa.Function]map[*ssa.BasicBlock]*loop
+}
+
+func newLoops() *loops {
+	return &loops{innermost: make(map[*ssa.Function]map[*ssa.BasicBlock]*loop)}
+}
+
+// counting returns the counting loop whose header is b, or nil if there is no such loop.
+// It is passed to the solver backend to summarize the counting loops.
+func (ls *loops) counting(b *ssa.BasicBlock) *solver.CountingLoop {
+	if l := ls.of(b); l != nil && l.header == b {
+		return l.counting
+	}
+	return nil
+}
+
+// of returns the innermost loop that contains b, or nil if b is not in a loop.
+func (ls *loops) of(b *ssa.BasicBlock) *loop {
+	fn := b.Parent()
+	innermost, ok := ls.innermost[fn]
+	if !ok {
+		innermost = findLoops(fn)
+		ls.innermost[fn] = innermost
+	}
+	return innermost[b]
+}
+
+// findLoops finds the natural loops of fn and returns the innermost loop of each block.
+// An edge from b to h is a back edge if h dominates b, and the loop of the header h
+// consists of the blocks that reach the back edges to h without passing through h.
+func findLoops(fn *ssa.Function) map[*ssa.BasicBlock]*loop {
+	headers := make(map[*ssa.BasicBlock]*loop)
+	var ordered []*loop
+	for _, b := range fn.Blocks {
+		for _, h := range b.Succs {
+			if !h.Dominates(b) {
+				continue
+			}
+			l, ok := headers[h]
+			if !ok {
+				l = &loop{header: h, body: map[*ssa.BasicBlock]bool{h: true}}
+				headers[h] = l
+				ordered = append(ordered, l)
+			}
+			stack := []*ssa.BasicBlock{b}
+			for len(stack) > 0 {
+				x := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if l.body[x] {
+					continue
+				}
+				l.body[x] = true
+				stack = append(stack, x.Preds...)
+			}
+		}
+	}
+
+	innermost := make(map[*ssa.BasicBlock]*loop)
+	for _, l := range ordered {
+		l.counting = countingLoop(l)
+		for b := range l.body {
+			// Natural loops with different headers are either disjoint or nested.
+			if inner, ok := innermost[b]; !ok || len(l.body) < len(inner.body) {
+				innermost[b] = l
+			}
+		}
+	}
+	return innermost
+}
+
+// countingLoop returns the summary of l if l is a simple counting loop, or nil otherwise.
+// The condition of the loop must hold to enter the loop, and the induction variable must be
+// an integer that starts from the same constant and changes by the same constant on all the back edges.
+func countingLoop(l *loop) *solver.CountingLoop {
+	ifInstr, ok := l.header.Instrs[len(l.header.Instrs)-1].(*ssa.If)
+	if !ok {
+		return nil
+	}
+	succs := l.header.Succs
+	if !l.body[succs[0]] || l.body[succs[1]] {
+		return nil
+	}
+	cond, ok := ifInstr.Cond.(*ssa.BinOp)
+	if !ok {
+		return nil
+	}
+	op := cond.Op
+	switch op {
+	case token.LSS, token.LEQ, token.GTR, token.GEQ, token.NEQ:
+	default:
+		return nil
+	}
+	x, bound := cond.X, cond.Y
+	if !l.isInvariant(bound) {
+		// The induction variable is on the right.
+		x, bound = bound, x
+		op = swapComparison(op)
+	}
+	if !l.isInvariant(bound) {
+		return nil
+	}
+	phi, ok := x.(*ssa.Phi)
+	if !ok || phi.Block() != l.header {
+		return nil
+	}
+	if ty, ok := phi.Type().Underlying().(*types.Basic); !ok || ty.Info()&types.IsInteger == 0 {
+		return nil
+	}
+	cl := &solver.CountingLoop{If: ifInstr, Induction: phi, Op: op, Bound: bound}
+	for i, edge := range phi.Edges {
+		if !l.body[l.header.Preds[i]] {
+			start, ok := edge.(*ssa.Const)
+			if !ok || cl.Start != nil && !constant.Compare(start.Value, token.EQL, cl.Start.Value) {
+				return nil
+			}
+			cl.Start = start
+			continue
+		}
+		step, decrement, ok := inductionStep(phi, edge)
+		if !ok || cl.Step != nil && (decrement != cl.Decrement || !constant.Compare(step.Value, token.EQL, cl.Step.Value)) {
+			return nil
+		}
+		cl.Step, cl.Decrement = step, decrement
+	}
+	if cl.Start == nil || cl.Step == nil {
+		return nil
+	}
+	return cl
+}
+
+// inductionStep returns the positive constant added to phi by edge, or subtracted if decrement is true.
+// It reports false if edge is not such an increment or decrement of phi.
+func inductionStep(phi *ssa.Phi, edge ssa.Value) (step *ssa.Const, decrement bool, ok bool) {
+	binop, ok := edge.(*ssa.BinOp)
+	if !ok {
+		return nil, false, false
+	}
+	var c *ssa.Const
+	switch {
+	case binop.Op == token.ADD && binop.X == phi:
+		c, ok = binop.Y.(*ssa.Const)
+	case binop.Op == token.ADD && binop.Y == phi:
+		c, ok = binop.X.(*ssa.Const)
+	case binop.Op == token.SUB && binop.X == phi:
+		c, ok = binop.Y.(*ssa.Const)
+		decrement = true
+	default:
+		return nil, false, false
+	}
+	if !ok {
+		return nil, false, false
+	}
+	switch constant.Sign(c.Value) {
+	case 0:
+		return nil, false, false
+	case -1:
+		c = ssa.NewConst(constant.UnaryOp(token.SUB, c.Value, 0), c.Type())
+		decrement = !decrement
+	}
+	return c, decrement, true
+}
+
+// swapComparison returns the comparison op with its operands swapped.
+func swapComparison(op token.Token) token.Token {
+	switch op {
+	case token.LSS:
+		return token.GTR
+	case token.LEQ:
+		return token.GEQ
+	case token.GTR:
+		return token.LSS
+	case token.GEQ:
+		return token.LEQ
+	}
+	return op
+}
+
+// isInvariant reports whether v does not change in l.
+func (l *loop) isInvariant(v ssa.Value) bool {
+	switch v := v.(type) {
+	case *ssa.Const, *ssa.Parameter:
+		return true
+	case ssa.Instruction:
+		return !l.body[v.Block()]
+	}
+	return false
+}
+
+// iterations returns the iteration of the innermost loop in which each branch was taken,
+// where positions are the indices of the instructions in the trace instrs at which the branches were taken.
+// The iteration is counted from 1 and reset when the loop is entered from outside,
+// and it is 0 for the branches not in loops.
+// The iterations are counted separately in each call frame so that a recursive call does not
+// continue the loops of its caller.
+func (ls *loops) iterations(instrs []ssa.Instruction, positions []int) []int {
+	iters := make([]int, len(positions))
+	var frames []*loopFrame
+	j := 0
+	for i, instr := range instrs {
+		if j == len(positions) {
+			break
+		}
+		b := instr.Block()
+		fn := b.Parent()
+		if b.Index == 0 && instr == b.Instrs[0] {
+			frames = append(frames, &loopFrame{fn: fn, counts: make(map[*loop]int)})
+		}
+		// Pop the frames unwound by a panic.
+		for len(frames) > 0 && frames[len(frames)-1].fn != fn {
+			frames = frames[:len(frames)-1]
+		}
+		if len(frames) == 0 {
+			// The trace starts in the middle of fn.
+			frames = append(frames, &loopFrame{fn: fn, counts: make(map[*loop]int)})
+		}
+		f := frames[len(frames)-1]
+		if instr == b.Instrs[0] {
+			if l := ls.of(b); l != nil && l.header == b {
+				if f.prev != nil && l.body[f.prev] {
+					f.counts[l]++
+				} else {
+					f.counts[l] = 1
+				}
+			}
+			f.prev = b
+		}
+		for j < len(positions) && positions[j] == i {
+			if l := ls.of(b); l != nil {
+				iters[j] = f.counts[l]
+			}
+			j++
+		}
+		if _, ok := instr.(*ssa.Return); ok {
+			frames = frames[:len(frames)-1]
+		}
+	}
+	return iters
+}
+
+// loopFrame is a call frame in a trace.
+type loopFrame struct {
+	fn *ssa.Function
+	// prev is the block that was executed last in the frame.
+	prev   *ssa.BasicBlock
+	counts map[*loop]int
+}
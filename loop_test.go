@@ -0,0 +1,146 @@
+package congo
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const loopSrc = `package p
+
+func count(n int) int {
+	s := 0
+	for i := 0; i < n; i++ {
+		s += i
+	}
+	return s
+}
+
+func halve(n int) int {
+	c := 0
+	for n > 1 {
+		n /= 2
+		c++
+	}
+	return c
+}
+
+func down(n int) int {
+	c := 0
+	for i := 10; n < i; i -= 3 {
+		c++
+	}
+	return c
+}
+
+func rec(n int) int {
+	s := 0
+	for i := 0; i < n; i++ {
+		s += rec(i)
+	}
+	return s
+}
+`
+
+func TestLoops(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", loopSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{Importer: importer.Default()}, fset,
+		types.NewPackage("p", ""), []*ast.File{file}, ssa.SanityCheckFunctions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls := newLoops()
+
+	// count: entry -> for.loop -> (for.body -> for.loop | for.done)
+	count := pkg.Func("count")
+	header := count.Blocks[0].Succs[0]
+	body, done := header.Succs[0], header.Succs[1]
+	l := ls.of(header)
+	if l == nil || l.header != header || !l.body[body] || l.body[done] {
+		t.Fatalf("wrong loop of count: %+v", l)
+	}
+	if cl := l.counting; cl == nil || cl.Induction.Block() != header || cl.Start.Int64() != 0 ||
+		cl.Step.Int64() != 1 || cl.Decrement || cl.Op != token.LSS || cl.Bound != count.Params[0] {
+		t.Errorf("wrong counting loop of count: %+v", cl)
+	}
+	if ls.counting(header) != l.counting || ls.counting(body) != nil {
+		t.Error("the counting loop of count should be found only at its header")
+	}
+	if ls.of(count.Blocks[0]) != nil {
+		t.Error("the entry block of count is not in a loop")
+	}
+
+	halve := pkg.Func("halve")
+	if l := ls.of(halve.Blocks[0].Succs[0]); l == nil || l.counting != nil {
+		t.Errorf("the loop of halve is not a counting loop: %+v", l)
+	}
+
+	// The induction variable of down is on the right of the condition, which is swapped.
+	down := pkg.Func("down")
+	if cl := ls.counting(down.Blocks[0].Succs[0]); cl == nil || cl.Start.Int64() != 10 ||
+		cl.Step.Int64() != 3 || !cl.Decrement || cl.Op != token.GTR || cl.Bound != down.Params[0] {
+		t.Errorf("wrong counting loop of down: %+v", cl)
+	}
+
+	// The trace of count(2).
+	cond := header.Instrs[len(header.Instrs)-1].(*ssa.If)
+	var instrs []ssa.Instruction
+	for _, b := range []*ssa.BasicBlock{count.Blocks[0], header, body, header, body, header, done} {
+		instrs = append(instrs, b.Instrs...)
+	}
+	var positions []int
+	for i, instr := range instrs {
+		if instr == cond {
+			positions = append(positions, i)
+		}
+	}
+	iters := ls.iterations(instrs, positions)
+	for j, iter := range []int{1, 2, 3} {
+		if iters[j] != iter {
+			t.Errorf("expected iteration %d for branch %d, actual %d", iter, j, iters[j])
+		}
+	}
+
+	// The trace of rec(1), which calls rec(0) in the first iteration.
+	rec := pkg.Func("rec")
+	recHeader := rec.Blocks[0].Succs[0]
+	recBody, recDone := recHeader.Succs[0], recHeader.Succs[1]
+	recCond := recHeader.Instrs[len(recHeader.Instrs)-1]
+	var call int
+	for k, instr := range recBody.Instrs {
+		if _, ok := instr.(*ssa.Call); ok {
+			call = k + 1
+		}
+	}
+	instrs = nil
+	for _, seq := range [][]ssa.Instruction{
+		rec.Blocks[0].Instrs, recHeader.Instrs, recBody.Instrs[:call],
+		rec.Blocks[0].Instrs, recHeader.Instrs, recDone.Instrs,
+		recBody.Instrs[call:], recHeader.Instrs, recDone.Instrs,
+	} {
+		instrs = append(instrs, seq...)
+	}
+	positions = nil
+	for i, instr := range instrs {
+		if instr == recCond {
+			positions = append(positions, i)
+		}
+	}
+	// The iterations are counted in each call frame.
+	iters = ls.iterations(instrs, positions)
+	for j, iter := range []int{1, 1, 2} {
+		if iters[j] != iter {
+			t.Errorf("rec: expected iteration %d for branch %d, actual %d", iter, j, iters[j])
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package congo
+
+import (
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Overflow is an integer operation or conversion in the target package that overflows with an input.
+type Overflow struct {
+	// Instr is the operation (*ssa.BinOp or *ssa.UnOp) or the conversion (*ssa.Convert) that overflows.
+	Instr ssa.Instruction
+	// Pos is the source position of Instr.
+	Pos token.Position
+	// Values are the values of the symbols with which Instr overflows.
+	Values []interface{}
+}
+
+func newOverflow(instr ssa.Instruction, values []interface{}) Overflow {
+	return Overflow{
+		Instr:  instr,
+		Pos:    instr.Parent().Prog.Fset.Position(instr.Pos()),
+		Values: values,
+	}
+}
+
+// overflowCandidates returns the indices of instrs whose overflows are to be checked:
+// the last occurrence of each instruction in pkg that has not been found to overflow yet.
+// The last occurrence is chosen since the operations in a loop (e.g., accumulation)
+// are more likely to overflow in later iterations.
+func overflowCandidates(instrs []ssa.Instruction, pkg *ssa.Package, found map[ssa.Instruction]bool) []int {
+	var indices []int
+	seen := make(map[ssa.Instruction]bool)
+	for i := len(instrs) - 1; i >= 0; i-- {
+		instr := instrs[i]
+		if seen[instr] || found[instr] || instr.Parent().Pkg != pkg {
+			continue
+		}
+		seen[instr] = true
+		indices = append(indices, i)
+	}
+	return indices
+}
@@ -0,0 +1,118 @@
+package congo
+
+import (
+	"github.com/ajalab/congo/solver"
+	"golang.org/x/tools/go/ssa"
+)
+
+// negationStatus is the result of an attempt to negate a branch.
+type negationStatus int
+
+const (
+	// negationSat means that the negated path condition was satisfiable.
+	negationSat negationStatus = iota
+	// negationUnsat means that the negated path condition was unsatisfiable.
+	negationUnsat
+	// negationUnknown means that the solver could not decide the negated path condition (e.g., timed out).
+	negationUnknown
+)
+
+func (s negationStatus) String() string {
+	switch s {
+	case negationSat:
+		return "sat"
+	case negationUnsat:
+		return "unsat"
+	case negationUnknown:
+		return "unknown"
+	}
+	return "invalid"
+}
+
+// decision is a direction taken at a branch.
+type decision struct {
+	instr ssa.Instruction
+	to    *ssa.BasicBlock
+}
+
+func decisionOf(b solver.Branch) decision {
+	return decision{instr: b.Instr(), to: b.To()}
+}
+
+// pathNode is a node of pathTree, which corresponds to a sequence of decisions from the root.
+type pathNode struct {
+	children map[decision]*pathNode
+	// negations records the attempts to negate the decisions taken after this node.
+	negations map[decision]negationStatus
+	// end reports whether a run ended at this node.
+	end bool
+}
+
+func newPathNode() *pathNode {
+	return &pathNode{
+		children:  make(map[decision]*pathNode),
+		negations: make(map[decision]negationStatus),
+	}
+}
+
+// pathTree is a prefix tree of the decisions of all the past runs.
+// It records which negations were attempted so that the same path prefix is not solved twice
+// and alternatives already taken by another run are not negated.
+type pathTree struct {
+	root *pathNode
+}
+
+func newPathTree() *pathTree {
+	return &pathTree{root: newPathNode()}
+}
+
+// insert adds the decisions of path to the tree.
+// It reports false if an identical path has been inserted before.
+func (t *pathTree) insert(path *Path) bool {
+	node := t.root
+	for _, b := range path.Branches {
+		d := decisionOf(b)
+		child, ok := node.children[d]
+		if !ok {
+			child = newPathNode()
+			node.children[d] = child
+		}
+		node = child
+	}
+	if node.end {
+		return false
+	}
+	node.end = true
+	return true
+}
+
+// node returns the node of the decisions before the branch of c.
+// The path of c must have been inserted.
+func (t *pathTree) node(c Candidate) *pathNode {
+	node := t.root
+	for _, b := range c.Path.Branches[:c.Index] {
+		node = node.children[decisionOf(b)]
+	}
+	return node
+}
+
+// explored reports whether the negation of c has been attempted,
+// or the alternative of the branch has been taken by another run.
+func (t *pathTree) explored(c Candidate) bool {
+	node := t.node(c)
+	d := decisionOf(c.Branch())
+	if _, ok := node.negations[d]; ok {
+		return true
+	}
+	for other := range node.children {
+		if other.instr == d.instr && other != d {
+			return true
+		}
+	}
+	return false
+}
+
+// record records the result of the negation of c.
+func (t *pathTree) record(c Candidate, status negationStatus) {
+	t.node(c).negations[decisionOf(c.Branch())] = status
+}
@@ -0,0 +1,52 @@
+package congo
+
+import (
+	"testing"
+
+	"github.com/ajalab/congo/solver"
+	"golang.org/x/tools/go/ssa"
+)
+
+// ifBranch is a branch of an if instruction that took the block to.
+type ifBranch struct {
+	instr     *ssa.If
+	to, other *ssa.BasicBlock
+}
+
+func (b *ifBranch) Instr() ssa.Instruction { return b.instr }
+func (b *ifBranch) To() *ssa.BasicBlock    { return b.to }
+func (b *ifBranch) Other() *ssa.BasicBlock { return b.other }
+
+func TestPathTree(t *testing.T) {
+	if0, if1 := &ssa.If{}, &ssa.If{}
+	t0, f0, t1, f1 := &ssa.BasicBlock{}, &ssa.BasicBlock{}, &ssa.BasicBlock{}, &ssa.BasicBlock{}
+	p0 := &Path{Branches: []solver.Branch{&ifBranch{if0, t0, f0}, &ifBranch{if1, t1, f1}}}
+	p1 := &Path{Branches: []solver.Branch{&ifBranch{if0, t0, f0}, &ifBranch{if1, f1, t1}}}
+	p2 := &Path{Branches: []solver.Branch{&ifBranch{if0, t0, f0}, &ifBranch{if1, t1, f1}}}
+
+	tree := newPathTree()
+	if !tree.insert(p0) {
+		t.Fatal("p0 must be a new path")
+	}
+	if tree.explored(Candidate{p0, 0}) || tree.explored(Candidate{p0, 1}) {
+		t.Fatal("no branch of p0 has been explored")
+	}
+	tree.record(Candidate{p0, 0}, negationUnsat)
+	if !tree.explored(Candidate{p0, 0}) {
+		t.Error("the negation of the first branch of p0 has been attempted")
+	}
+
+	if !tree.insert(p1) {
+		t.Fatal("p1 must be a new path")
+	}
+	if !tree.explored(Candidate{p0, 1}) || !tree.explored(Candidate{p1, 1}) {
+		t.Error("both sides of the second branch have been taken")
+	}
+	if !tree.explored(Candidate{p1, 0}) {
+		t.Error("the negation of the first branch of p1 has been attempted with p0")
+	}
+
+	if tree.insert(p2) {
+		t.Error("p2 is identical to p0")
+	}
+}
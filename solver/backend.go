@@ -0,0 +1,262 @@
+package solver
+
+import (
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ssa"
+)
+
+const (
+	z3SymbolPrefixForSymbol string = "symbol-"
+)
+
+// Solver is a solver that holds the path condition of a trace.
+type Solver interface {
+	// Branches returns the branches in the trace.
+	Branches() []Branch
+	// Positions returns the indices of the instructions in the trace at which the branches were taken.
+	Positions() []int
+	// Solve solves the path condition whose negate-th branch is negated and returns concrete values for symbols.
+	// A nil solution means that the symbol should keep the value in the current run.
+	// It returns UnsatError if the condition is unsatisfiable,
+	// and UnknownError if the solver could not decide it.
+	Solve(negate int) ([]Solution, error)
+	// Dump writes the query of Solve(negate) to w as a standalone SMT-LIB2 script,
+	// where the assertions are annotated with the branches they come from.
+	Dump(w io.Writer, negate int) error
+	// Overflows returns the integer operations and conversions in the trace that may overflow.
+	// They are recorded only if the backend checks overflows (see Backend.SetOverflow).
+	Overflows() []ssa.Instruction
+	// SolveOverflow solves the path condition up to the i-th instruction of Overflows
+	// with the condition that the instruction overflows, and returns concrete values for symbols as Solve does.
+	SolveOverflow(i int) ([]Solution, error)
+	// Close releases the solver.
+	Close()
+}
+
+// Backend is a solver backend, which creates the solvers of the traces of a target.
+type Backend interface {
+	// NewSolver returns a new solver for the trace instrs.
+	// concreteTypes are the candidates for dynamic types of symbolic interface values.
+	// If isComplete is false, the last instruction of the trace is regarded as the cause of a panic.
+	NewSolver(symbols []ssa.Value, concreteTypes []types.Type, instrs []ssa.Instruction, isComplete bool) (Solver, error)
+	// SetTimeout sets the time limit of each query. No limit is imposed if it is zero.
+	SetTimeout(timeout time.Duration)
+	// SetReadable makes the solvers prefer readable models, i.e., those with small absolute values of integers,
+	// short strings, and printable characters, as long as they are found within the time limit.
+	SetReadable(readable bool)
+	// SetOverflow makes the solvers record the conditions under which
+	// the integer operations and conversions in the traces overflow (see mayOverflow).
+	SetOverflow(overflow bool)
+	// SetLoops makes the solvers summarize the conditions of the counting loops returned by loops (see CountingLoop).
+	// No loop is summarized if loops is nil.
+	SetLoops(loops Loops)
+	// Interrupt interrupts the query being solved, which results in UnknownError.
+	// It is safe to call Interrupt from another goroutine while the backend is not closed.
+	Interrupt()
+	// Close releases the backend. The solvers created by the backend are still to be closed.
+	Close()
+}
+
+// backend is a registered backend.
+type backend struct {
+	// new creates the backend with the command line of the solver process (if any).
+	new func(command string) (Backend, error)
+	// supports reports whether symbols of the type can be represented by the backend.
+	supports func(ty types.Type) bool
+}
+
+// backends maps the names of the available backends to their registrations.
+var backends = make(map[string]backend)
+
+// defaultBackend is the name of the backend used by default.
+var defaultBackend string
+
+func registerBackend(name string, new func(command string) (Backend, error), supports func(types.Type) bool, isDefault bool) {
+	backends[name] = backend{new: new, supports: supports}
+	if isDefault || defaultBackend == "" {
+		defaultBackend = name
+	}
+}
+
+// NewBackend returns a new backend of the given name.
+// command is the command line of the solver process for the backends that run an external solver,
+// where the default one is used if it is empty.
+func NewBackend(name, command string) (Backend, error) {
+	b, ok := backends[name]
+	if !ok {
+		return nil, errors.Errorf("unknown solver backend: %s (available: %v)", name, Backends())
+	}
+	return b.new(command)
+}
+
+// CheckSymbols returns an error if the backend of the given name cannot represent some of the symbols.
+func CheckSymbols(name string, symbols []ssa.Value) error {
+	b, ok := backends[name]
+	if !ok {
+		return errors.Errorf("unknown solver backend: %s (available: %v)", name, Backends())
+	}
+	for _, symbol := range symbols {
+		if !b.supports(symbol.Type()) {
+			return errors.Errorf("symbols of type %v are not supported by the %s backend", symbol.Type(), name)
+		}
+	}
+	return nil
+}
+
+// Backends returns the names of the available backends.
+// The z3 backend, which uses libz3 via cgo, is not available if cgo is disabled or the noz3 build tag is given.
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultBackend returns the name of the default backend,
+// which is z3 if it is available and smtlib otherwise.
+func DefaultBackend() string {
+	return defaultBackend
+}
+
+// isSupportedBasic reports whether values of ty can be symbolic.
+func isSupportedBasic(ty *types.Basic) bool {
+	return ty.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) > 0
+}
+
+func sizeOfBasicKind(k types.BasicKind) uint {
+	switch k {
+	case types.Int:
+		fallthrough
+	case types.Uint:
+		return strconv.IntSize
+	case types.Int8:
+		fallthrough
+	case types.Uint8:
+		return 8
+	case types.Int16:
+		fallthrough
+	case types.Uint16:
+		return 16
+	case types.Int32:
+		fallthrough
+	case types.Uint32:
+		return 32
+	case types.Int64:
+		fallthrough
+	case types.Uint64:
+		return 64
+	}
+	return 0
+}
+
+// integerSolution returns a solution of integer type ty whose bits are u.
+func integerSolution(u uint64, ty *types.Basic) (Solution, error) {
+	sol := Definite{ty: ty}
+	switch ty.Kind() {
+	case types.Int:
+		sol.value = int(u)
+	case types.Int8:
+		sol.value = int8(u)
+	case types.Int16:
+		sol.value = int16(u)
+	case types.Int32:
+		sol.value = int32(u)
+	case types.Int64:
+		sol.value = int64(u)
+	case types.Uint:
+		sol.value = uint(u)
+	case types.Uint8:
+		sol.value = uint8(u)
+	case types.Uint16:
+		sol.value = uint16(u)
+	case types.Uint32:
+		sol.value = uint32(u)
+	case types.Uint64:
+		sol.value = uint64(u)
+	default:
+		return nil, errors.Errorf("not supported integer: %v (%v)", ty, ty.Kind())
+	}
+	return sol, nil
+}
+
+// mayOverflow reports whether instr is an integer operation or conversion whose result may overflow:
+// addition, subtraction, multiplication, left shift, negation of a signed integer,
+// and conversion to an integer type that cannot represent all the values of the original type.
+func mayOverflow(instr ssa.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ssa.BinOp:
+		switch instr.Op {
+		case token.ADD, token.SUB, token.MUL, token.SHL:
+			_, ok := overflowType(instr.Type())
+			return ok
+		}
+	case *ssa.UnOp:
+		ty, ok := overflowType(instr.Type())
+		return ok && instr.Op == token.SUB && ty.Info()&types.IsUnsigned == 0
+	case *ssa.Convert:
+		from, ok := overflowType(instr.X.Type())
+		if !ok {
+			return false
+		}
+		to, ok := overflowType(instr.Type())
+		return ok && isNarrowing(from, to)
+	}
+	return false
+}
+
+// overflowType returns the underlying type of ty if it is an integer type of a fixed size.
+func overflowType(ty types.Type) (*types.Basic, bool) {
+	basicTy, ok := ty.Underlying().(*types.Basic)
+	if !ok || basicTy.Info()&types.IsInteger == 0 || sizeOfBasicKind(basicTy.Kind()) == 0 {
+		return nil, false
+	}
+	return basicTy, true
+}
+
+// isNarrowing reports whether some values of integer type from are not representable in integer type to.
+func isNarrowing(from, to *types.Basic) bool {
+	fromSize, toSize := sizeOfBasicKind(from.Kind()), sizeOfBasicKind(to.Kind())
+	fromUnsigned, toUnsigned := from.Info()&types.IsUnsigned > 0, to.Info()&types.IsUnsigned > 0
+	switch {
+	case fromUnsigned == toUnsigned:
+		return toSize < fromSize
+	case fromUnsigned:
+		// The sign bit of the signed type is not available for the value.
+		return toSize <= fromSize
+	default:
+		// Negative values are not representable in the unsigned type.
+		return true
+	}
+}
+
+// overflowPrefix returns the number of the branches taken before the instruction at pos of the trace,
+// which constitute the path condition of the instruction.
+func overflowPrefix(positions []int, pos int) int {
+	return sort.SearchInts(positions, pos)
+}
+
+// UnsatError is an error describing that the constraints were unsatisfied.
+type UnsatError struct{}
+
+func (ue UnsatError) Error() string {
+	return "unsat"
+}
+
+// UnknownError is an error returned when the solver could not decide whether the constraints are satisfiable
+// (e.g., the query timed out).
+type UnknownError struct {
+	Reason string
+}
+
+func (ue UnknownError) Error() string {
+	return "unknown: " + ue.Reason
+}
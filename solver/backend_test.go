@@ -0,0 +1,325 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os/exec"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const backendTestSrc = `package p
+
+func Branch(x, y int) int {
+	if x > 10 {
+		if x < 5 {
+			return 0
+		}
+		return x - y
+	}
+	return y
+}
+
+func Div(x, y int) int {
+	return x / y
+}
+
+func Add(x, y int8) int8 {
+	return x + y
+}
+
+type ID uint64
+
+func Const(x int64, id ID) int {
+	if x == -1<<40 && id == 1<<63+1 {
+		return 1
+	}
+	return 0
+}
+
+func Loop(n int) int {
+	i := 0
+	for i < n {
+		i += 2
+	}
+	if i == 10 {
+		return 1
+	}
+	return 0
+}
+`
+
+// buildTestPackage builds the SSA package of src.
+func buildTestPackage(t *testing.T, src string) *ssa.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{Importer: importer.Default()}, fset,
+		types.NewPackage("p", ""), []*ast.File{f}, ssa.SanityCheckFunctions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+// blockTrace returns the trace that runs through the blocks of fn in order.
+func blockTrace(fn *ssa.Function, blocks ...int) []ssa.Instruction {
+	var instrs []ssa.Instruction
+	for _, b := range blocks {
+		instrs = append(instrs, fn.Blocks[b].Instrs...)
+	}
+	return instrs
+}
+
+// panicTrace returns the trace of the entry block of fn that panics at the first instruction satisfying cause.
+func panicTrace(fn *ssa.Function, cause func(ssa.Instruction) bool) []ssa.Instruction {
+	for i, instr := range fn.Blocks[0].Instrs {
+		if cause(instr) {
+			return fn.Blocks[0].Instrs[:i+1]
+		}
+	}
+	return nil
+}
+
+// newTestBackends returns the z3 and smtlib backends.
+// The test is skipped if the solver process of the smtlib backend is not on PATH.
+func newTestBackends(t *testing.T, overflow bool) map[string]Backend {
+	t.Helper()
+	if _, err := exec.LookPath("z3"); err != nil {
+		t.Skip("z3 is not on PATH")
+	}
+	backends := make(map[string]Backend)
+	for _, name := range []string{"z3", "smtlib"} {
+		b, err := NewBackend(name, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.SetOverflow(overflow)
+		backends[name] = b
+		t.Cleanup(b.Close)
+	}
+	return backends
+}
+
+// newTestSolvers returns the solvers of the trace instrs of fn created by each backend,
+// and checks that they have the same branches at the same positions.
+func newTestSolvers(t *testing.T, backends map[string]Backend, fn *ssa.Function, instrs []ssa.Instruction, isComplete bool) map[string]Solver {
+	t.Helper()
+	symbols := make([]ssa.Value, len(fn.Params))
+	for i, param := range fn.Params {
+		symbols[i] = param
+	}
+	solvers := make(map[string]Solver)
+	for name, b := range backends {
+		s, err := b.NewSolver(symbols, nil, instrs, isComplete)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		solvers[name] = s
+		t.Cleanup(s.Close)
+	}
+	z3, smtlib := solvers["z3"], solvers["smtlib"]
+	if len(z3.Branches()) != len(smtlib.Branches()) {
+		t.Fatalf("branches: z3 %v, smtlib %v", z3.Branches(), smtlib.Branches())
+	}
+	for i, b := range z3.Branches() {
+		if b.Instr() != smtlib.Branches()[i].Instr() || z3.Positions()[i] != smtlib.Positions()[i] {
+			t.Errorf("branch %d: z3 %v at %d, smtlib %v at %d",
+				i, b, z3.Positions()[i], smtlib.Branches()[i], smtlib.Positions()[i])
+		}
+	}
+	return solvers
+}
+
+// checkSolutions checks that the values of the solutions satisfy want.
+// The value of a nil solution, which keeps the current value of the symbol, is nil.
+func checkSolutions(t *testing.T, name string, sols []Solution, err error, want func(values []interface{}) bool) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	values := make([]interface{}, len(sols))
+	for i, sol := range sols {
+		if sol == nil {
+			continue
+		}
+		d, ok := sol.(Definite)
+		if !ok {
+			t.Errorf("%s: solution %d is %T, want Definite", name, i, sol)
+			return
+		}
+		values[i] = d.value
+	}
+	if !want(values) {
+		t.Errorf("%s: unexpected solution %v", name, values)
+	}
+}
+
+func TestBackendsBranch(t *testing.T) {
+	fn := buildTestPackage(t, backendTestSrc).Func("Branch")
+	backends := newTestBackends(t, false)
+
+	// x > 10 is false.
+	solvers := newTestSolvers(t, backends, fn, blockTrace(fn, 0, 2), true)
+	for name, s := range solvers {
+		sols, err := s.Solve(0)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			return values[0].(int) > 10
+		})
+	}
+
+	// x > 10 is true and x < 5 is false.
+	solvers = newTestSolvers(t, backends, fn, blockTrace(fn, 0, 1, 4), true)
+	for name, s := range solvers {
+		sols, err := s.Solve(0)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			return values[0].(int) <= 10
+		})
+		if _, err := s.Solve(1); err != (UnsatError{}) {
+			t.Errorf("%s: x > 10 && x < 5 should be unsatisfiable, got %v", name, err)
+		}
+	}
+}
+
+func TestBackendsDivZero(t *testing.T) {
+	fn := buildTestPackage(t, backendTestSrc).Func("Div")
+	backends := newTestBackends(t, false)
+
+	// The division succeeds.
+	solvers := newTestSolvers(t, backends, fn, blockTrace(fn, 0), true)
+	for name, s := range solvers {
+		if n := len(s.Branches()); n != 1 {
+			t.Fatalf("%s: %d branches, want 1", name, n)
+		}
+		sols, err := s.Solve(0)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			return values[1].(int) == 0
+		})
+	}
+
+	// The division panics.
+	instrs := panicTrace(fn, func(instr ssa.Instruction) bool {
+		binop, ok := instr.(*ssa.BinOp)
+		return ok && binop.Op == token.QUO
+	})
+	solvers = newTestSolvers(t, backends, fn, instrs, false)
+	for name, s := range solvers {
+		if n := len(s.Branches()); n != 1 {
+			t.Fatalf("%s: %d branches, want 1", name, n)
+		}
+		sols, err := s.Solve(0)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			return values[1].(int) != 0
+		})
+	}
+}
+
+func TestBackendsOverflow(t *testing.T) {
+	fn := buildTestPackage(t, backendTestSrc).Func("Add")
+	backends := newTestBackends(t, true)
+
+	solvers := newTestSolvers(t, backends, fn, blockTrace(fn, 0), true)
+	for name, s := range solvers {
+		if n := len(s.Overflows()); n != 1 {
+			t.Fatalf("%s: %d overflows, want 1", name, n)
+		}
+		sols, err := s.SolveOverflow(0)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			sum := int(values[0].(int8)) + int(values[1].(int8))
+			return sum != int(int8(sum))
+		})
+	}
+}
+
+func TestBackendsConst(t *testing.T) {
+	fn := buildTestPackage(t, backendTestSrc).Func("Const")
+	backends := newTestBackends(t, false)
+
+	// x == -1<<40 is false.
+	solvers := newTestSolvers(t, backends, fn, blockTrace(fn, 0, 2), true)
+	for name, s := range solvers {
+		sols, err := s.Solve(0)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			return values[0].(int64) == -1<<40
+		})
+	}
+
+	// x == -1<<40 is true and id == 1<<63+1 is false.
+	solvers = newTestSolvers(t, backends, fn, blockTrace(fn, 0, 3, 2), true)
+	for name, s := range solvers {
+		sols, err := s.Solve(1)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			// x may keep the current value, which satisfies the first condition.
+			return (values[0] == nil || values[0].(int64) == -1<<40) && values[1].(uint64) == 1<<63+1
+		})
+	}
+}
+
+func TestBackendsLoop(t *testing.T) {
+	fn := buildTestPackage(t, backendTestSrc).Func("Loop")
+	header := fn.Blocks[0].Succs[0]
+	phi := header.Instrs[0].(*ssa.Phi)
+	cl := &CountingLoop{
+		If:        header.Instrs[len(header.Instrs)-1].(*ssa.If),
+		Induction: phi,
+		Start:     phi.Edges[0].(*ssa.Const),
+		Step:      ssa.NewConst(constant.MakeInt64(2), phi.Type()),
+		Op:        token.LSS,
+		Bound:     fn.Params[0],
+	}
+	backends := newTestBackends(t, false)
+	for _, b := range backends {
+		b.SetLoops(func(b *ssa.BasicBlock) *CountingLoop {
+			if b == header {
+				return cl
+			}
+			return nil
+		})
+	}
+
+	// The trace of Loop(3), which exits the loop at the third evaluation of the condition with i == 4.
+	body, done := header.Succs[0], header.Succs[1]
+	var instrs []ssa.Instruction
+	for _, b := range []*ssa.BasicBlock{fn.Blocks[0], header, body, header, body, header, done, done.Succs[1]} {
+		instrs = append(instrs, b.Instrs...)
+	}
+	solvers := newTestSolvers(t, backends, fn, instrs, true)
+	for name, s := range solvers {
+		if n := len(s.Branches()); n != 3 {
+			t.Fatalf("%s: %d branches, want 3", name, n)
+		}
+		if _, ok := s.Branches()[1].(*BranchLoop); !ok {
+			t.Fatalf("%s: branch 1 is %T, want *BranchLoop", name, s.Branches()[1])
+		}
+		// The loop is not entered.
+		sols, err := s.Solve(0)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			return values[0].(int) <= 0
+		})
+		// The loop exits after another number of iterations.
+		sols, err = s.Solve(1)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			n := values[0].(int)
+			return n > 0 && n != 3 && n != 4
+		})
+		// The trip count is solved so that i == 10 at the exit.
+		sols, err = s.Solve(2)
+		checkSolutions(t, name, sols, err, func(values []interface{}) bool {
+			n := values[0].(int)
+			return n == 9 || n == 10
+		})
+	}
+}
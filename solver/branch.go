@@ -1,6 +1,10 @@
 package solver
 
-import "golang.org/x/tools/go/ssa"
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/ssa"
+)
 
 // Branch represents a branch appeared in a running trace.
 // This includes instructions that may cause a panic (e.g., pointer dereference) as well as ordinary branching by *ssa.If.
@@ -45,6 +49,28 @@ func (b *BranchIf) Other() *ssa.BasicBlock {
 	return succs[0]
 }
 
+// BranchLoop represents the exit of a summarized counting loop (see CountingLoop).
+// It stands for the conditions of the loop evaluated after the first iteration, and
+// its negation makes the loop exit after another number of iterations.
+type BranchLoop struct {
+	instr *ssa.If
+}
+
+// Instr returns ssa.Instruction value for the branch.
+func (b *BranchLoop) Instr() ssa.Instruction {
+	return b.instr
+}
+
+// To returns ssa.BasicBlock that the branch took, which is the exit of the loop.
+func (b *BranchLoop) To() *ssa.BasicBlock {
+	return b.instr.Block().Succs[1]
+}
+
+// Other returns ssa.BasicBlock that the branch did not take, which is the body of the loop.
+func (b *BranchLoop) Other() *ssa.BasicBlock {
+	return b.instr.Block().Succs[0]
+}
+
 // BranchDeref represents a branching (success or panic) caused by
 // dereference (*ssa.UnOp or *ssa.FieldAddr).
 type BranchDeref struct {
@@ -73,3 +99,133 @@ func (b *BranchDeref) Other() *ssa.BasicBlock {
 	}
 	return b.instr.Block()
 }
+
+// BranchBounds represents a branching (success or panic) caused by
+// a bounds check of indexing or slicing (*ssa.IndexAddr, *ssa.Index, or *ssa.Slice).
+type BranchBounds struct {
+	instr   ssa.Instruction
+	success bool
+}
+
+// Instr returns ssa.Instruction value for the branch.
+func (b *BranchBounds) Instr() ssa.Instruction {
+	return b.instr
+}
+
+// To returns ssa.BasicBlock that the branch took.
+func (b *BranchBounds) To() *ssa.BasicBlock {
+	if b.success {
+		return b.instr.Block()
+	}
+	return nil
+}
+
+// Other returns ssa.BasicBlock that the branch did not take.
+func (b *BranchBounds) Other() *ssa.BasicBlock {
+	if b.success {
+		return nil
+	}
+	return b.instr.Block()
+}
+
+// BranchDivZero represents a branching (success or panic) caused by
+// an integer division or remainder (*ssa.BinOp) whose divisor may be zero.
+type BranchDivZero struct {
+	instr   *ssa.BinOp
+	success bool
+}
+
+// Instr returns ssa.Instruction value for the branch.
+func (b *BranchDivZero) Instr() ssa.Instruction {
+	return b.instr
+}
+
+// To returns ssa.BasicBlock that the branch took.
+func (b *BranchDivZero) To() *ssa.BasicBlock {
+	if b.success {
+		return b.instr.Block()
+	}
+	return nil
+}
+
+// Other returns ssa.BasicBlock that the branch did not take.
+func (b *BranchDivZero) Other() *ssa.BasicBlock {
+	if b.success {
+		return nil
+	}
+	return b.instr.Block()
+}
+
+// BranchTypeAssert represents a branching (success or panic) caused by
+// a type assertion without comma-ok (*ssa.TypeAssert).
+type BranchTypeAssert struct {
+	instr   *ssa.TypeAssert
+	success bool
+}
+
+// Instr returns ssa.Instruction value for the branch.
+func (b *BranchTypeAssert) Instr() ssa.Instruction {
+	return b.instr
+}
+
+// To returns ssa.BasicBlock that the branch took.
+func (b *BranchTypeAssert) To() *ssa.BasicBlock {
+	if b.success {
+		return b.instr.Block()
+	}
+	return nil
+}
+
+// Other returns ssa.BasicBlock that the branch did not take.
+func (b *BranchTypeAssert) Other() *ssa.BasicBlock {
+	if b.success {
+		return nil
+	}
+	return b.instr.Block()
+}
+
+// BranchInvoke represents a dispatch of a dynamic method call (*ssa.Call in invoke mode)
+// to callee, which is nil if the receiver was a nil interface value.
+type BranchInvoke struct {
+	instr  *ssa.Call
+	callee *ssa.Function
+}
+
+// Instr returns ssa.Instruction value for the branch.
+func (b *BranchInvoke) Instr() ssa.Instruction {
+	return b.instr
+}
+
+// To returns ssa.BasicBlock that the branch took.
+func (b *BranchInvoke) To() *ssa.BasicBlock {
+	if b.callee == nil {
+		return nil
+	}
+	return b.callee.Blocks[0]
+}
+
+// Other returns ssa.BasicBlock that the branch did not take.
+// The other callee is not determined until the solver chooses another dynamic type.
+func (b *BranchInvoke) Other() *ssa.BasicBlock {
+	return nil
+}
+
+// describeBranch returns a description of the i-th branch b with its instruction and source position,
+// which annotates the dumped queries.
+func describeBranch(i int, b Branch) string {
+	instr := b.Instr()
+	pos := instr.Pos()
+	if ifInstr, ok := instr.(*ssa.If); ok && !pos.IsValid() {
+		// If instructions have no position, so that of the condition is used.
+		pos = ifInstr.Cond.Pos()
+	}
+	position := "-"
+	if pos.IsValid() {
+		position = instr.Parent().Prog.Fset.Position(pos).String()
+	}
+	to := "panic"
+	if b.To() != nil {
+		to = "block " + b.To().String()
+	}
+	return fmt.Sprintf("branch %d at %s in %s: %s -> %s", i, position, instr.Parent(), instr, to)
+}
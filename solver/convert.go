@@ -0,0 +1,291 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <stdlib.h>
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Strings are sequences of bytes in the solver; each character of a Z3 string
+// corresponds to a byte of the Go string.
+
+// z3MakeString returns a string literal. Unlike Z3_mk_string, escape sequences
+// and NUL bytes in str are kept as they are.
+func z3MakeString(ctx C.Z3_context, str string) C.Z3_ast {
+	cs := C.CString(str)
+	defer C.free(unsafe.Pointer(cs))
+	return C.Z3_mk_lstring(ctx, C.uint(len(str)), cs)
+}
+
+// z3MakeByteString returns the condition that every character of a string str is a byte.
+// Z3 strings may contain characters beyond 0xFF, which do not correspond to Go strings.
+func z3MakeByteString(ctx C.Z3_context, str C.Z3_ast) C.Z3_ast {
+	bytes := C.Z3_mk_re_range(ctx, z3MakeString(ctx, "\x00"), z3MakeString(ctx, "\xff"))
+	return C.Z3_mk_seq_in_re(ctx, str, C.Z3_mk_re_star(ctx, bytes))
+}
+
+// z3MakeByteSort returns the sort of bytes.
+func z3MakeByteSort(ctx C.Z3_context) C.Z3_sort {
+	return C.Z3_mk_bv_sort(ctx, 8)
+}
+
+// z3MakeResize converts an integer x to an integer of size bits.
+// x is sign-extended unless unsigned is true.
+func z3MakeResize(ctx C.Z3_context, x C.Z3_ast, size C.uint, unsigned bool) C.Z3_ast {
+	xsize := C.Z3_get_bv_sort_size(ctx, C.Z3_get_sort(ctx, x))
+	switch {
+	case xsize > size:
+		return C.Z3_mk_extract(ctx, size-1, 0, x)
+	case xsize < size:
+		if unsigned {
+			return C.Z3_mk_zero_ext(ctx, size-xsize, x)
+		}
+		return C.Z3_mk_sign_ext(ctx, size-xsize, x)
+	}
+	return x
+}
+
+// stringFunc returns the declaration of a string function name with an argument of sort argSort.
+// It is used for functions that are not exposed by the C API of Z3 (e.g., str.to_code).
+func (s *Z3Solver) stringFunc(name, argSort string) C.Z3_func_decl {
+	if decl, ok := s.context.stringFuncs[name]; ok {
+		return decl
+	}
+	src := C.CString(fmt.Sprintf("(declare-const x %s) (assert (= (%s x) (%[2]s x)))", argSort, name))
+	defer C.free(unsafe.Pointer(src))
+	assertions := C.Z3_parse_smtlib2_string(s.ctx, src, 0, nil, nil, 0, nil, nil)
+	// The vector is kept alive so that the declaration is not deleted.
+	C.Z3_ast_vector_inc_ref(s.ctx, assertions)
+	eq := C.Z3_to_app(s.ctx, C.Z3_ast_vector_get(s.ctx, assertions, 0))
+	app := C.Z3_to_app(s.ctx, C.Z3_get_app_arg(s.ctx, eq, 0))
+	decl := C.Z3_get_app_decl(s.ctx, app)
+	s.context.stringFuncs[name] = decl
+	return decl
+}
+
+// byteAt returns the i-th byte of a string str.
+// The result is unspecified if i is out of range.
+func (s *Z3Solver) byteAt(str, i C.Z3_ast) C.Z3_ast {
+	c := C.Z3_mk_seq_at(s.ctx, str, C.Z3_mk_bv2int(s.ctx, i, C.bool(false)))
+	code := C.Z3_mk_app(s.ctx, s.stringFunc("str.to_code", "String"), 1, &c)
+	return C.Z3_mk_int2bv(s.ctx, 8, code)
+}
+
+// byteString converts a byte b to a string of length 1.
+func (s *Z3Solver) byteString(b C.Z3_ast) C.Z3_ast {
+	code := C.Z3_mk_bv2int(s.ctx, b, C.bool(false))
+	return C.Z3_mk_app(s.ctx, s.stringFunc("str.from_code", "Int"), 1, &code)
+}
+
+// z3MakeConcat concatenates strings.
+func z3MakeConcat(ctx C.Z3_context, strs []C.Z3_ast) C.Z3_ast {
+	switch len(strs) {
+	case 0:
+		return z3MakeString(ctx, "")
+	case 1:
+		return strs[0]
+	}
+	return C.Z3_mk_seq_concat(ctx, C.uint(len(strs)), &strs[0])
+}
+
+// z3MakeValidRune returns the condition that an integer x of type ty
+// is a valid Unicode code point (i.e., string(x) is not "�").
+func z3MakeValidRune(ctx C.Z3_context, x C.Z3_ast, ty *types.Basic) C.Z3_ast {
+	x = z3MakeResize(ctx, x, 64, ty.Info()&types.IsUnsigned > 0)
+	sort := C.Z3_mk_bv_sort(ctx, 64)
+	num := func(n int) C.Z3_ast { return C.Z3_mk_int64(ctx, C.int64_t(n), sort) }
+	// Surrogate halves are not valid code points.
+	surrogate := []C.Z3_ast{
+		C.Z3_mk_bvuge(ctx, x, num(0xD800)),
+		C.Z3_mk_bvule(ctx, x, num(0xDFFF)),
+	}
+	args := []C.Z3_ast{
+		// Negative values are regarded as large unsigned integers.
+		C.Z3_mk_bvule(ctx, x, num(0x10FFFF)),
+		C.Z3_mk_not(ctx, C.Z3_mk_and(ctx, 2, &surrogate[0])),
+	}
+	return C.Z3_mk_and(ctx, 2, &args[0])
+}
+
+// runeString returns a string of the UTF-8 representation of an integer x of type ty.
+// It follows utf8.EncodeRune.
+func (s *Z3Solver) runeString(x C.Z3_ast, ty *types.Basic) C.Z3_ast {
+	r := z3MakeResize(s.ctx, x, 32, ty.Info()&types.IsUnsigned > 0)
+	sort := C.Z3_mk_bv_sort(s.ctx, 32)
+	num := func(n int) C.Z3_ast { return C.Z3_mk_int64(s.ctx, C.int64_t(n), sort) }
+	// b returns the byte tag | (r >> shift) & mask.
+	b := func(tag, shift, mask int) C.Z3_ast {
+		v := C.Z3_mk_bvand(s.ctx, C.Z3_mk_bvlshr(s.ctx, r, num(shift)), num(mask))
+		return C.Z3_mk_extract(s.ctx, 7, 0, C.Z3_mk_bvor(s.ctx, v, num(tag)))
+	}
+	encodings := []struct {
+		cond  C.Z3_ast
+		bytes []C.Z3_ast
+	}{
+		{C.Z3_mk_true(s.ctx), []C.Z3_ast{b(0xF0, 18, 0x07), b(0x80, 12, 0x3F), b(0x80, 6, 0x3F), b(0x80, 0, 0x3F)}},
+		{C.Z3_mk_bvult(s.ctx, r, num(0x10000)), []C.Z3_ast{b(0xE0, 12, 0x0F), b(0x80, 6, 0x3F), b(0x80, 0, 0x3F)}},
+		{C.Z3_mk_bvult(s.ctx, r, num(0x800)), []C.Z3_ast{b(0xC0, 6, 0x1F), b(0x80, 0, 0x3F)}},
+		{C.Z3_mk_bvult(s.ctx, r, num(0x80)), []C.Z3_ast{b(0x00, 0, 0x7F)}},
+	}
+	var str C.Z3_ast
+	for _, e := range encodings {
+		strs := make([]C.Z3_ast, len(e.bytes))
+		for j, b := range e.bytes {
+			strs[j] = s.byteString(b)
+		}
+		if str == nil {
+			str = z3MakeConcat(s.ctx, strs)
+			continue
+		}
+		str = C.Z3_mk_ite(s.ctx, e.cond, z3MakeConcat(s.ctx, strs), str)
+	}
+	// Invalid code points are converted to "\uFFFD".
+	return C.Z3_mk_ite(s.ctx, z3MakeValidRune(s.ctx, x, ty), str, z3MakeString(s.ctx, "\uFFFD"))
+}
+
+// convert loads a type conversion.
+func (s *Z3Solver) convert(instr *ssa.Convert) {
+	to := instr.Type().Underlying()
+	switch from := instr.X.Type().Underlying().(type) {
+	case *types.Basic:
+		if !isSupportedBasic(from) {
+			return
+		}
+		x := s.get(instr.X)
+		if x == nil {
+			return
+		}
+		switch to := to.(type) {
+		case *types.Basic:
+			if ast := s.convertBasic(x, from, to); ast != nil {
+				s.asts[instr] = ast
+			}
+		case *types.Slice:
+			if from.Info()&types.IsString > 0 {
+				s.stringToSlice(instr, x, to)
+			}
+		}
+	case *types.Slice:
+		sl, ok := s.slices[instr.X]
+		if !ok || sl.array == nil {
+			return
+		}
+		if to, ok := to.(*types.Basic); ok && to.Info()&types.IsString > 0 {
+			s.asts[instr] = s.sliceToString(sl, from)
+		}
+	}
+}
+
+// convertBasic converts x from a basic type to another basic type.
+// It returns nil if the conversion is not supported.
+func (s *Z3Solver) convertBasic(x C.Z3_ast, from, to *types.Basic) C.Z3_ast {
+	fromInfo, toInfo := from.Info(), to.Info()
+	rm := z3MakeRoundingMode(s.ctx)
+	switch {
+	case fromInfo&types.IsInteger > 0 && toInfo&types.IsInteger > 0:
+		return z3MakeResize(s.ctx, x, C.uint(sizeOfBasicKind(to.Kind())), fromInfo&types.IsUnsigned > 0)
+	case fromInfo&types.IsInteger > 0 && toInfo&types.IsFloat > 0:
+		if fromInfo&types.IsUnsigned > 0 {
+			return C.Z3_mk_fpa_to_fp_unsigned(s.ctx, rm, x, newBasicSort(s.ctx, to))
+		}
+		return C.Z3_mk_fpa_to_fp_signed(s.ctx, rm, x, newBasicSort(s.ctx, to))
+	case fromInfo&types.IsFloat > 0 && toInfo&types.IsInteger > 0:
+		// The fractional part is discarded (truncation towards zero).
+		rtz := C.Z3_mk_fpa_round_toward_zero(s.ctx)
+		size := C.uint(sizeOfBasicKind(to.Kind()))
+		if toInfo&types.IsUnsigned > 0 {
+			return C.Z3_mk_fpa_to_ubv(s.ctx, rtz, x, size)
+		}
+		return C.Z3_mk_fpa_to_sbv(s.ctx, rtz, x, size)
+	case fromInfo&types.IsFloat > 0 && toInfo&types.IsFloat > 0:
+		if from.Kind() == to.Kind() {
+			return x
+		}
+		return C.Z3_mk_fpa_to_fp_float(s.ctx, rm, x, newBasicSort(s.ctx, to))
+	case fromInfo&types.IsInteger > 0 && toInfo&types.IsString > 0:
+		return s.runeString(x, from)
+	case fromInfo&types.IsString > 0 && toInfo&types.IsString > 0:
+		return x
+	}
+	return nil
+}
+
+// stringToSlice loads a conversion of a string str to a byte or rune slice.
+func (s *Z3Solver) stringToSlice(instr *ssa.Convert, str C.Z3_ast, ty *types.Slice) {
+	elemTy, ok := elemType(ty)
+	if !ok {
+		return
+	}
+	switch elemTy.Kind() {
+	case types.Byte:
+		i := z3MkFreshConst(s.ctx, "i", z3MakeIntSort(s.ctx))
+		app := C.Z3_to_app(s.ctx, i)
+		l := C.Z3_mk_int2bv(s.ctx, strconv.IntSize, C.Z3_mk_seq_length(s.ctx, str))
+		s.slices[instr] = sliceAST{
+			array:  C.Z3_mk_lambda_const(s.ctx, 1, &app, s.byteAt(str, i)),
+			offset: z3MakeIntNumeral(s.ctx, 0),
+			len:    l,
+			cap:    l,
+		}
+	case types.Rune:
+		// The runes are given as fresh symbols constrained to be encoded into str.
+		// We assume that str is a valid UTF-8 string of at most maxSymbolicSliceLen runes.
+		intSort := z3MakeIntSort(s.ctx)
+		arraySort := C.Z3_mk_array_sort(s.ctx, intSort, newBasicSort(s.ctx, elemTy))
+		l := z3MkFreshConst(s.ctx, "len(runes)", intSort)
+		sl := sliceAST{
+			array:  z3MkFreshConst(s.ctx, "runes", arraySort),
+			offset: z3MakeIntNumeral(s.ctx, 0),
+			len:    l,
+			cap:    l,
+		}
+		args := []C.Z3_ast{
+			C.Z3_mk_bvsge(s.ctx, l, z3MakeIntNumeral(s.ctx, 0)),
+			C.Z3_mk_bvsle(s.ctx, l, z3MakeIntNumeral(s.ctx, maxSymbolicSliceLen)),
+			C.Z3_mk_eq(s.ctx, s.sliceToString(sl, ty), str),
+		}
+		for j := 0; j < maxSymbolicSliceLen; j++ {
+			r := C.Z3_mk_select(s.ctx, sl.array, z3MakeIntNumeral(s.ctx, j))
+			args = append(args, C.Z3_mk_implies(s.ctx,
+				C.Z3_mk_bvslt(s.ctx, z3MakeIntNumeral(s.ctx, j), l),
+				z3MakeValidRune(s.ctx, r, elemTy),
+			))
+		}
+		s.axioms = append(s.axioms, C.Z3_mk_and(s.ctx, C.uint(len(args)), &args[0]))
+		s.slices[instr] = sl
+	}
+}
+
+// sliceToString returns a string converted from a byte or rune slice sl.
+// Elements beyond maxSymbolicSliceLen are not taken into account.
+func (s *Z3Solver) sliceToString(sl sliceAST, ty *types.Slice) C.Z3_ast {
+	elemTy, _ := elemType(ty)
+	elems := make([]C.Z3_ast, maxSymbolicSliceLen)
+	for i := range elems {
+		elems[i] = C.Z3_mk_select(s.ctx, sl.array, C.Z3_mk_bvadd(s.ctx, sl.offset, z3MakeIntNumeral(s.ctx, i)))
+	}
+	// Each element is converted if it is within the slice.
+	for i, e := range elems {
+		if elemTy.Kind() == types.Byte {
+			e = s.byteString(e)
+		} else {
+			e = s.runeString(e, elemTy)
+		}
+		cond := C.Z3_mk_bvslt(s.ctx, z3MakeIntNumeral(s.ctx, i), sl.len)
+		elems[i] = C.Z3_mk_ite(s.ctx, cond, e, z3MakeString(s.ctx, ""))
+	}
+	return z3MakeConcat(s.ctx, elems)
+}
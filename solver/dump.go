@@ -0,0 +1,72 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <stdlib.h>
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// Dump writes the query of Solve(negate) to w as a standalone SMT-LIB2 script.
+// The constraints are those actually solved, i.e., the ones relevant to the negated branch,
+// and each of them is annotated with the branch it comes from.
+func (s *Z3Solver) Dump(w io.Writer, negate int) error {
+	labels := make(map[C.Z3_ast]string)
+	constraints := make([]C.Z3_ast, 0, len(s.axioms)+negate)
+	label := func(c C.Z3_ast, l string) {
+		constraints = append(constraints, c)
+		if _, ok := labels[c]; !ok {
+			labels[c] = l
+		}
+	}
+	for _, axiom := range s.axioms {
+		label(axiom, "axiom")
+	}
+	for i := 0; i < negate; i++ {
+		label(s.getBranchAST(i, false), describeBranch(i, s.branches[i]))
+	}
+	negated := s.getBranchAST(negate, true)
+	constraints, _ = s.relevant(constraints, negated)
+
+	// The declarations are taken from the benchmark of the whole query,
+	// which precede the assertions.
+	empty := C.CString("")
+	defer C.free(unsafe.Pointer(empty))
+	status := C.CString("unknown")
+	defer C.free(unsafe.Pointer(status))
+	var assumptions *C.Z3_ast
+	if len(constraints) > 0 {
+		assumptions = &constraints[0]
+	}
+	benchmark := C.GoString(C.Z3_benchmark_to_smtlib_string(s.ctx, empty, empty, status, empty,
+		C.uint(len(constraints)), assumptions, negated))
+	if i := strings.Index(benchmark, "(assert"); i >= 0 {
+		benchmark = benchmark[:i]
+	}
+
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(benchmark, "\n") {
+		// The comment of the benchmark name is dropped.
+		if !strings.HasPrefix(line, ";") {
+			b.WriteString(line)
+		}
+	}
+	for _, c := range constraints {
+		fmt.Fprintf(&b, "; %s\n(assert %s)\n", labels[c], C.GoString(C.Z3_ast_to_string(s.ctx, c)))
+	}
+	fmt.Fprintf(&b, "; negated %s\n(assert %s)\n", describeBranch(negate, s.branches[negate]),
+		C.GoString(C.Z3_ast_to_string(s.ctx, negated)))
+	b.WriteString("(check-sat)\n(get-model)\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
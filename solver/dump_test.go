@@ -0,0 +1,82 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	"bufio"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	fn := buildTestPackage(t, queryTestSrc).Func("Slice")
+	context := NewZ3Context()
+	defer context.Close()
+	s := newQueryTestSolver(t, context, fn)
+
+	var b strings.Builder
+	if err := s.Dump(&b, 3); err != nil {
+		t.Fatal(err)
+	}
+	script := b.String()
+
+	// The script consists of well-formed commands.
+	commands := make(map[string]int)
+	declared := make(map[string]bool)
+	r := bufio.NewReader(strings.NewReader(script))
+	for {
+		v, err := readSexp(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("the script does not parse: %v\n%s", err, script)
+		}
+		l, ok := v.([]sexp)
+		if !ok || len(l) == 0 {
+			t.Fatalf("unexpected command %v\n%s", v, script)
+		}
+		name, _ := l[0].(string)
+		commands[name]++
+		if name == "declare-fun" || name == "declare-const" {
+			declared[strings.Trim(l[1].(string), "|")] = true
+		}
+	}
+	if commands["check-sat"] != 1 || commands["get-model"] != 1 {
+		t.Errorf("the script should check the satisfiability and get the model once:\n%s", script)
+	}
+	// x > 0 and y > x are kept, w > 0 is sliced away, and z > y is negated.
+	if n := commands["assert"]; n != 3 {
+		t.Errorf("%d assertions, want 3:\n%s", n, script)
+	}
+	for i, want := range []bool{true, true, true, false} {
+		if name := z3SymbolPrefixForSymbol + string(rune('0'+i)); declared[name] != want {
+			t.Errorf("%s should be declared: %v\n%s", name, want, script)
+		}
+	}
+	for _, want := range []string{"; branch 0 at ", "; branch 1 at ", "; negated branch 3 at "} {
+		if !strings.Contains(script, want) {
+			t.Errorf("the script should contain %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "; branch 2 at ") {
+		t.Errorf("the script should not contain the sliced-away branch 2:\n%s", script)
+	}
+
+	// The script is accepted by the solver as it is.
+	if _, err := exec.LookPath("z3"); err != nil {
+		t.Skip("z3 is not on PATH")
+	}
+	cmd := exec.Command("z3", "-in", "-smt2")
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("z3: %v\n%s", err, out)
+	}
+	if !strings.HasPrefix(string(out), "sat\n") {
+		t.Errorf("z3 should find the script satisfiable:\n%s", out)
+	}
+}
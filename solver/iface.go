@@ -0,0 +1,212 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <stdlib.h>
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"go/types"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ssa"
+)
+
+// ifaceAST is a symbolic representation of an interface value.
+// The dynamic type of the value is given by an integer AST (stored in asts) called type ID.
+// The type ID is 0 if the value is nil and i+1 if the dynamic type is types[i].
+// values[i] is a pseudo value that holds the dynamic value when the dynamic type is types[i].
+type ifaceAST struct {
+	types  []types.Type
+	values []ssa.Value
+}
+
+// dynamic is a pseudo value that represents the dynamic value of an interface value
+// whose dynamic type is ty.
+type dynamic struct {
+	ssa.Value
+	ty types.Type
+}
+
+func (v *dynamic) Type() types.Type {
+	return v.ty
+}
+
+// implementations returns the concrete types given to the solver that implement the interface type ty.
+func (s *Z3Solver) implementations(ty *types.Interface) []types.Type {
+	var impls []types.Type
+	for _, t := range s.concreteTypes {
+		if types.Implements(t, ty) {
+			impls = append(impls, t)
+		}
+	}
+	return impls
+}
+
+// loadIfaceSymbol loads a symbolic interface value.
+// The dynamic type is chosen among the concrete types implementing the interface.
+func (s *Z3Solver) loadIfaceSymbol(symbol ssa.Value, name string) {
+	impls := s.implementations(symbol.Type().Underlying().(*types.Interface))
+	id := C.Z3_mk_const(s.ctx, z3MkStringSymbol(s.ctx, "type("+name+")"), C.Z3_mk_int_sort(s.ctx))
+	args := []C.Z3_ast{
+		C.Z3_mk_ge(s.ctx, id, z3MakeTypeID(s.ctx, 0)),
+		C.Z3_mk_le(s.ctx, id, z3MakeTypeID(s.ctx, len(impls))),
+	}
+	s.axioms = append(s.axioms, C.Z3_mk_and(s.ctx, 2, &args[0]))
+
+	iface := &ifaceAST{
+		types:  impls,
+		values: make([]ssa.Value, len(impls)),
+	}
+	for i, ty := range impls {
+		v := &dynamic{Value: symbol, ty: ty}
+		iface.values[i] = v
+		s.loadSymbol(v, name+".("+ty.String()+")")
+		if _, ok := ty.(*types.Pointer); ok {
+			// A nil pointer in a non-nil interface cannot be expressed
+			// by a composite literal in generated tests.
+			s.axioms = append(s.axioms, C.Z3_mk_not(s.ctx, C.Z3_mk_eq(s.ctx, s.asts[v], z3MakeTypeID(s.ctx, 0))))
+		}
+	}
+	s.asts[symbol] = id
+	s.ifaces[symbol] = iface
+}
+
+// z3MakeTypeID returns a type ID of interface values.
+func z3MakeTypeID(ctx C.Z3_context, i int) C.Z3_ast {
+	return C.Z3_mk_int(ctx, C.int(i), C.Z3_mk_int_sort(ctx))
+}
+
+// typeCond returns the condition that the dynamic type of x is asserted by ty.
+// If ty is an interface type, the condition holds if the dynamic type implements ty.
+// Otherwise, the dynamic type must be identical to ty.
+func (s *Z3Solver) typeCond(x ssa.Value, ty types.Type) C.Z3_ast {
+	iface := s.ifaces[x]
+	id := s.asts[x]
+	var args []C.Z3_ast
+	for i, t := range iface.types {
+		var ok bool
+		if ifaceTy, isIface := ty.Underlying().(*types.Interface); isIface {
+			ok = types.Implements(t, ifaceTy)
+		} else {
+			ok = types.Identical(t, ty)
+		}
+		if ok {
+			args = append(args, C.Z3_mk_eq(s.ctx, id, z3MakeTypeID(s.ctx, i+1)))
+		}
+	}
+	if len(args) == 0 {
+		return C.Z3_mk_false(s.ctx)
+	}
+	return C.Z3_mk_or(s.ctx, C.uint(len(args)), &args[0])
+}
+
+// dynamicValue returns the pseudo value that holds the dynamic value of x of type ty.
+func (s *Z3Solver) dynamicValue(x ssa.Value, ty types.Type) (ssa.Value, bool) {
+	iface := s.ifaces[x]
+	for i, t := range iface.types {
+		if types.Identical(t, ty) {
+			return iface.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// typeAssert loads a type assertion of a symbolic interface value.
+// A type assertion without comma-ok is recorded as a branch since it panics on failure.
+func (s *Z3Solver) typeAssert(instr *ssa.TypeAssert) {
+	if _, ok := s.ifaces[instr.X]; !ok {
+		return
+	}
+	cond := s.typeCond(instr.X, instr.AssertedType)
+	var result ssa.Value = instr
+	if instr.CommaOk {
+		result = &component{instr, 0}
+		commaOk := &component{instr, 1}
+		s.asts[commaOk] = cond
+		s.tuples[instr] = []ssa.Value{result, commaOk}
+	} else {
+		s.addBranch(&BranchTypeAssert{
+			instr:   instr,
+			success: true,
+		}, cond)
+	}
+
+	if _, ok := instr.AssertedType.Underlying().(*types.Interface); ok {
+		// The result shares the dynamic value with x, or is nil on failure.
+		s.ifaces[result] = s.ifaces[instr.X]
+		s.asts[result] = C.Z3_mk_ite(s.ctx, cond, s.asts[instr.X], z3MakeTypeID(s.ctx, 0))
+		return
+	}
+	if v, ok := s.dynamicValue(instr.X, instr.AssertedType); ok {
+		s.bind(result, v)
+	}
+}
+
+// invoke loads a dynamic method call to the callee traced in the next instruction.
+// It reports whether the callee was traced.
+func (s *Z3Solver) invoke(instr *ssa.Call, next ssa.Instruction) bool {
+	call := instr.Call
+	if next == nil {
+		return false
+	}
+	fn := next.Parent()
+	if fn == nil || fn.Signature.Recv() == nil || fn.Name() != call.Method.Name() ||
+		len(fn.Blocks) == 0 || fn.Blocks[0].Instrs[0] != next {
+		return false
+	}
+	recv := fn.Params[0]
+	if _, ok := s.ifaces[call.Value]; ok {
+		cond := s.typeCond(call.Value, recv.Type())
+		s.addBranch(&BranchInvoke{
+			instr:  instr,
+			callee: fn,
+		}, cond)
+		if v, ok := s.dynamicValue(call.Value, recv.Type()); ok {
+			s.bind(recv, v)
+		}
+	}
+	for j, arg := range call.Args {
+		s.bind(fn.Params[j+1], arg)
+	}
+	return true
+}
+
+// addNilInvokeBranch appends a branch of a dynamic method call of a nil interface value x.
+func (s *Z3Solver) addNilInvokeBranch(instr *ssa.Call, x ssa.Value) {
+	if _, ok := s.ifaces[x]; !ok {
+		return
+	}
+	s.addBranch(&BranchInvoke{
+		instr: instr,
+	}, C.Z3_mk_eq(s.ctx, s.asts[x], z3MakeTypeID(s.ctx, 0)))
+}
+
+// getIfaceSolution returns a solution of an interface value consisting of its dynamic type and value.
+func (s *Z3Solver) getIfaceSolution(m C.Z3_model, v ssa.Value, iface *ifaceAST) (Solution, error) {
+	ast, err := s.getASTFromModel(m, v)
+	if err != nil {
+		return nil, err
+	}
+	var i C.int
+	if ok := bool(C.Z3_get_numeral_int(s.ctx, ast, &i)); !ok {
+		return nil, errors.New("Z3_get_numeral_int: could not get an int representation of the type ID")
+	}
+	if i == 0 {
+		return Definite{ty: v.Type(), value: nil}, nil
+	}
+	if int(i) > len(iface.types) {
+		return nil, errors.Errorf("invalid type ID of an interface value: %d", i)
+	}
+	dyn := iface.values[i-1]
+	sol, _ := s.getSolutionFromModel(m, dyn)
+	if sol == nil {
+		sol = Indefinite{ty: dyn.Type()}
+	}
+	return Definite{ty: v.Type(), value: sol}, nil
+}
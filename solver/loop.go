@@ -0,0 +1,37 @@
+package solver
+
+import (
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// CountingLoop is a simple counting loop, whose condition at the header compares an induction variable,
+// which starts from a constant and is incremented or decremented by a constant, with a bound that does not change in the loop.
+// The loop is entered if the condition holds, i.e., the then block of the condition is in the loop and the else block is not.
+//
+// The conditions of a counting loop evaluated after the first iteration are summarized into a BranchLoop at the exit,
+// which solves the trip count of the loop from Start, Step, and Bound instead of negating the iterations one by one.
+type CountingLoop struct {
+	// If is the condition of the loop at the header.
+	If *ssa.If
+	// Induction is the induction variable, which is a φ-node of an integer type at the header.
+	Induction *ssa.Phi
+	// Start is the value of Induction when the loop is entered.
+	Start *ssa.Const
+	// Step is the positive constant added to Induction in each iteration, or subtracted if Decrement is true.
+	Step      *ssa.Const
+	Decrement bool
+	// Op is the comparison of the condition, where Induction is on the left and Bound is on the right.
+	Op    token.Token
+	Bound ssa.Value
+}
+
+// Loops returns the counting loop whose header is the block, or nil if there is no such loop.
+type Loops func(header *ssa.BasicBlock) *CountingLoop
+
+// loopInstance is a counting loop in a call frame of a trace, whose depth is the number of the calls entered.
+type loopInstance struct {
+	depth  int
+	header *ssa.BasicBlock
+}
@@ -0,0 +1,290 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <stdlib.h>
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"fmt"
+	"go/types"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ssa"
+)
+
+// maxSymbolicMapLen is the maximum number of entries in symbolic maps.
+const maxSymbolicMapLen = 8
+
+// mapAST is a symbolic representation of a map.
+// A map given as a symbol consists of n (<= maxSymbolicMapLen) entries (keys[i], values[i]) with distinct keys.
+// Updates made to the map afterwards are recorded in updates.
+// values is nil if the element type is not supported by the solver.
+type mapAST struct {
+	keyType  *types.Basic
+	elemType *types.Basic
+	keys     []C.Z3_ast
+	values   []C.Z3_ast
+	n        C.Z3_ast
+	updates  []mapUpdate
+	len      C.Z3_ast
+}
+
+// mapUpdate is an assignment or a deletion of an entry.
+type mapUpdate struct {
+	key     C.Z3_ast
+	value   C.Z3_ast
+	present bool
+}
+
+// mapTypes returns the key and element types of a map type ty.
+// The key type must be supported by the solver.
+func mapTypes(ty types.Type) (*types.Basic, *types.Basic, bool) {
+	mapTy, ok := ty.Underlying().(*types.Map)
+	if !ok {
+		return nil, nil, false
+	}
+	isSupported := func(ty types.Type) (*types.Basic, bool) {
+		basicTy, ok := ty.Underlying().(*types.Basic)
+		if !ok || !isSupportedBasic(basicTy) {
+			return nil, false
+		}
+		return basicTy, true
+	}
+	// Floating-point keys are not supported since NaN and -0 need special treatment.
+	keyTy, ok := isSupported(mapTy.Key())
+	if !ok || keyTy.Info()&types.IsFloat > 0 {
+		return nil, nil, false
+	}
+	elemTy, _ := isSupported(mapTy.Elem())
+	return keyTy, elemTy, true
+}
+
+// loadMapSymbol loads a symbolic map.
+func (s *Z3Solver) loadMapSymbol(symbol ssa.Value, name string) {
+	keyTy, elemTy, ok := mapTypes(symbol.Type())
+	if !ok {
+		return
+	}
+	n := C.Z3_mk_const(s.ctx, z3MkStringSymbol(s.ctx, "len("+name+")"), z3MakeIntSort(s.ctx))
+	m := &mapAST{
+		keyType:  keyTy,
+		elemType: elemTy,
+		keys:     make([]C.Z3_ast, maxSymbolicMapLen),
+		n:        n,
+		len:      n,
+	}
+	if elemTy != nil {
+		m.values = make([]C.Z3_ast, maxSymbolicMapLen)
+	}
+	for i := 0; i < maxSymbolicMapLen; i++ {
+		m.keys[i] = C.Z3_mk_const(s.ctx, z3MkStringSymbol(s.ctx, fmt.Sprintf("%s.key%d", name, i)), newBasicSort(s.ctx, keyTy))
+		if m.values != nil {
+			m.values[i] = C.Z3_mk_const(s.ctx, z3MkStringSymbol(s.ctx, fmt.Sprintf("%s.value%d", name, i)), newBasicSort(s.ctx, elemTy))
+		}
+	}
+
+	// 0 <= n <= maxSymbolicMapLen and keys of the first n entries are distinct.
+	args := []C.Z3_ast{
+		C.Z3_mk_bvsge(s.ctx, n, z3MakeIntNumeral(s.ctx, 0)),
+		C.Z3_mk_bvsle(s.ctx, n, z3MakeIntNumeral(s.ctx, maxSymbolicMapLen)),
+	}
+	s.axioms = append(s.axioms, C.Z3_mk_and(s.ctx, 2, &args[0]))
+	for j := 1; j < maxSymbolicMapLen; j++ {
+		for i := 0; i < j; i++ {
+			s.axioms = append(s.axioms, C.Z3_mk_implies(s.ctx,
+				C.Z3_mk_bvslt(s.ctx, z3MakeIntNumeral(s.ctx, j), n),
+				C.Z3_mk_not(s.ctx, C.Z3_mk_eq(s.ctx, m.keys[i], m.keys[j])),
+			))
+		}
+	}
+	s.maps[symbol] = m
+}
+
+// newEmptyMap returns an empty map of type ty.
+func (s *Z3Solver) newEmptyMap(ty types.Type) (*mapAST, bool) {
+	keyTy, elemTy, ok := mapTypes(ty)
+	if !ok {
+		return nil, false
+	}
+	zero := z3MakeIntNumeral(s.ctx, 0)
+	return &mapAST{
+		keyType:  keyTy,
+		elemType: elemTy,
+		n:        zero,
+		len:      zero,
+	}, true
+}
+
+// makeMap loads a creation of an empty map.
+func (s *Z3Solver) makeMap(instr *ssa.MakeMap) {
+	if m, ok := s.newEmptyMap(instr.Type()); ok {
+		s.maps[instr] = m
+	}
+}
+
+// lookup returns the element for key k and whether k is present in m.
+// The element is nil if the element type is not supported.
+func (s *Z3Solver) lookup(m *mapAST, k C.Z3_ast) (C.Z3_ast, C.Z3_ast) {
+	found := C.Z3_mk_false(s.ctx)
+	var value C.Z3_ast
+	if m.elemType != nil {
+		value = z3MakeZero(s.ctx, m.elemType)
+	}
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		args := []C.Z3_ast{
+			C.Z3_mk_bvslt(s.ctx, z3MakeIntNumeral(s.ctx, i), m.n),
+			C.Z3_mk_eq(s.ctx, m.keys[i], k),
+		}
+		hit := C.Z3_mk_and(s.ctx, 2, &args[0])
+		found = C.Z3_mk_ite(s.ctx, hit, C.Z3_mk_true(s.ctx), found)
+		if value != nil {
+			value = C.Z3_mk_ite(s.ctx, hit, m.values[i], value)
+		}
+	}
+	for _, u := range m.updates {
+		hit := C.Z3_mk_eq(s.ctx, u.key, k)
+		present := C.Z3_mk_false(s.ctx)
+		if u.present {
+			present = C.Z3_mk_true(s.ctx)
+		}
+		found = C.Z3_mk_ite(s.ctx, hit, present, found)
+		if value != nil {
+			value = C.Z3_mk_ite(s.ctx, hit, u.value, value)
+		}
+	}
+	return value, found
+}
+
+// update records an assignment (present = true) or a deletion (present = false) of an entry with key k.
+func (s *Z3Solver) update(m *mapAST, k, v C.Z3_ast, present bool) {
+	_, found := s.lookup(m, k)
+	one := z3MakeIntNumeral(s.ctx, 1)
+	if present {
+		m.len = C.Z3_mk_ite(s.ctx, found, m.len, C.Z3_mk_bvadd(s.ctx, m.len, one))
+	} else {
+		m.len = C.Z3_mk_ite(s.ctx, found, C.Z3_mk_bvsub(s.ctx, m.len, one), m.len)
+	}
+	if m.elemType != nil && v == nil {
+		// The assigned value is not symbolic; we leave it unconstrained.
+		sort := newBasicSort(s.ctx, m.elemType)
+		v = z3MkFreshConst(s.ctx, "map-update", sort)
+	}
+	m.updates = append(m.updates, mapUpdate{key: k, value: v, present: present})
+}
+
+// mapLookup loads a lookup of a map or an indexing of a string.
+func (s *Z3Solver) mapLookup(instr *ssa.Lookup) {
+	if isString(instr.X.Type()) {
+		s.stringIndex(instr)
+		return
+	}
+	m, ok := s.maps[instr.X]
+	if !ok {
+		return
+	}
+	k := s.get(instr.Index)
+	if k == nil {
+		return
+	}
+	value, found := s.lookup(m, k)
+	if !instr.CommaOk {
+		if value != nil {
+			s.asts[instr] = value
+		}
+		return
+	}
+	// The first component is nil if the element is not symbolic.
+	elems := []ssa.Value{nil, &component{instr, 1}}
+	if value != nil {
+		elems[0] = &component{instr, 0}
+		s.asts[elems[0]] = value
+	}
+	s.asts[elems[1]] = found
+	s.tuples[instr] = elems
+}
+
+// mapUpdate loads an assignment to a map.
+func (s *Z3Solver) mapUpdate(instr *ssa.MapUpdate) {
+	m, ok := s.maps[instr.Map]
+	if !ok {
+		return
+	}
+	k := s.get(instr.Key)
+	if k == nil {
+		return
+	}
+	var v C.Z3_ast
+	if m.elemType != nil {
+		if ast, ok := s.asts[instr.Value]; ok {
+			v = ast
+		} else if c, ok := instr.Value.(*ssa.Const); ok {
+			v = s.getConstAST(c)
+		}
+	}
+	s.update(m, k, v, true)
+}
+
+// mapDelete loads a deletion of an entry.
+func (s *Z3Solver) mapDelete(m ssa.Value, key ssa.Value) {
+	mAST, ok := s.maps[m]
+	if !ok {
+		return
+	}
+	k := s.get(key)
+	if k == nil {
+		return
+	}
+	s.update(mAST, k, nil, false)
+}
+
+func (s *Z3Solver) getMapSolution(m C.Z3_model, ty types.Type, mAST *mapAST) (Solution, error) {
+	eval := func(ast C.Z3_ast) (C.Z3_ast, error) {
+		var result C.Z3_ast
+		if ok := C.Z3_model_eval(s.ctx, m, ast, C.bool(true), &result); !C.bool(ok) {
+			return nil, errors.New("failed to extract a map entry from the model")
+		}
+		return result, nil
+	}
+	l, err := eval(mAST.n)
+	if err != nil {
+		return nil, err
+	}
+	var n C.int64_t
+	if ok := bool(C.Z3_get_numeral_int64(s.ctx, l, &n)); !ok {
+		return nil, errors.New("Z3_get_numeral_int64: could not get an int64 representation of the length")
+	}
+	if n < 0 || n > C.int64_t(len(mAST.keys)) {
+		return nil, errors.Errorf("invalid length of a map: %d", n)
+	}
+
+	entries := make([]mapEntry, n)
+	for i := range entries {
+		k, err := eval(mAST.keys[i])
+		if err != nil {
+			return nil, err
+		}
+		entries[i].key, err = s.getBasicSolution(k, mAST.keyType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get a key of the entry %d", i)
+		}
+		if mAST.values == nil {
+			entries[i].value = Indefinite{ty: ty.Underlying().(*types.Map).Elem()}
+			continue
+		}
+		v, err := eval(mAST.values[i])
+		if err != nil {
+			return nil, err
+		}
+		entries[i].value, err = s.getBasicSolution(v, mAST.elemType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get a value of the entry %d", i)
+		}
+	}
+	return Definite{ty: ty, value: entries}, nil
+}
@@ -0,0 +1,100 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Overflows returns the integer operations and conversions in the trace that may overflow.
+func (s *Z3Solver) Overflows() []ssa.Instruction {
+	return s.overflows
+}
+
+// SolveOverflow solves the path condition up to the i-th instruction of Overflows
+// with the condition that the instruction overflows.
+func (s *Z3Solver) SolveOverflow(i int) ([]Solution, error) {
+	n := overflowPrefix(s.positions, s.overflowPositions[i])
+	constraints := make([]C.Z3_ast, 0, len(s.axioms)+n)
+	constraints = append(constraints, s.axioms...)
+	for j := 0; j < n; j++ {
+		constraints = append(constraints, s.getBranchAST(j, false))
+	}
+	return s.solve(constraints, s.overflowConds[i])
+}
+
+// addOverflow records the condition under which instr overflows if it is not trivial.
+func (s *Z3Solver) addOverflow(instr ssa.Instruction) {
+	cond := s.overflowCond(instr)
+	if cond == nil || C.Z3_get_bool_value(s.ctx, C.Z3_simplify(s.ctx, cond)) != C.Z3_L_UNDEF {
+		return
+	}
+	s.overflows = append(s.overflows, instr)
+	s.overflowPositions = append(s.overflowPositions, s.pos)
+	s.overflowConds = append(s.overflowConds, cond)
+}
+
+// overflowCond returns the condition under which instr overflows.
+// It returns nil if the result of instr is not symbolic.
+func (s *Z3Solver) overflowCond(instr ssa.Instruction) C.Z3_ast {
+	v := instr.(ssa.Value)
+	r, ok := s.asts[v]
+	if !ok || r == nil {
+		return nil
+	}
+	ty, _ := overflowType(v.Type())
+	signed := ty.Info()&types.IsUnsigned == 0
+	isSigned := C.bool(signed)
+
+	// none returns the negation of the conjunction of the conditions that rule out overflows.
+	none := func(conds ...C.Z3_ast) C.Z3_ast {
+		return C.Z3_mk_not(s.ctx, C.Z3_mk_and(s.ctx, C.uint(len(conds)), &conds[0]))
+	}
+	switch instr := instr.(type) {
+	case *ssa.BinOp:
+		x, y := s.get(instr.X), s.get(instr.Y)
+		switch instr.Op {
+		case token.ADD:
+			if signed {
+				return none(C.Z3_mk_bvadd_no_overflow(s.ctx, x, y, isSigned), C.Z3_mk_bvadd_no_underflow(s.ctx, x, y))
+			}
+			return none(C.Z3_mk_bvadd_no_overflow(s.ctx, x, y, isSigned))
+		case token.SUB:
+			if signed {
+				return none(C.Z3_mk_bvsub_no_overflow(s.ctx, x, y), C.Z3_mk_bvsub_no_underflow(s.ctx, x, y, isSigned))
+			}
+			return none(C.Z3_mk_bvsub_no_underflow(s.ctx, x, y, isSigned))
+		case token.MUL:
+			if signed {
+				return none(C.Z3_mk_bvmul_no_overflow(s.ctx, x, y, isSigned), C.Z3_mk_bvmul_no_underflow(s.ctx, x, y))
+			}
+			return none(C.Z3_mk_bvmul_no_overflow(s.ctx, x, y, isSigned))
+		case token.SHL:
+			// The shift overflows if shifting the result back does not restore the operand.
+			back := z3MakeShift(s.ctx, r, y, ty.Info(), token.SHR)
+			return C.Z3_mk_not(s.ctx, C.Z3_mk_eq(s.ctx, back, x))
+		}
+	case *ssa.UnOp:
+		return none(C.Z3_mk_bvneg_no_overflow(s.ctx, s.get(instr.X)))
+	case *ssa.Convert:
+		// The conversion overflows if the operand and the result differ as integers of a sufficient size.
+		from := instr.X.Type().Underlying().(*types.Basic)
+		size := sizeOfBasicKind(from.Kind())
+		if toSize := sizeOfBasicKind(ty.Kind()); toSize > size {
+			size = toSize
+		}
+		x := z3MakeResize(s.ctx, s.get(instr.X), C.uint(size+1), from.Info()&types.IsUnsigned > 0)
+		return C.Z3_mk_not(s.ctx, C.Z3_mk_eq(s.ctx, x, z3MakeResize(s.ctx, r, C.uint(size+1), !signed)))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <stdlib.h>
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// queryResult is a cached result of a query.
+type queryResult struct {
+	sat       bool
+	solutions []Solution
+}
+
+// symbolIndexPattern matches the names of Z3 constants that represent (a part of) a symbol,
+// such as "symbol-0", "*symbol-0", "len(symbol-1)", and "symbol-2.key0".
+var symbolIndexPattern = regexp.MustCompile(z3SymbolPrefixForSymbol + `(\d+)`)
+
+// unionFind is a disjoint-set forest over integers.
+type unionFind map[int]int
+
+func (u unionFind) find(x int) int {
+	p, ok := u[x]
+	if !ok || p == x {
+		return x
+	}
+	r := u.find(p)
+	u[x] = r
+	return r
+}
+
+func (u unionFind) union(x, y int) {
+	u[u.find(x)] = u.find(y)
+}
+
+// constants returns the IDs of the uninterpreted constants in ast.
+// The constants that represent symbols are also reported by the indices of the symbols.
+func (s *Z3Solver) constants(ast C.Z3_ast) ([]int, []int) {
+	var consts, symbols []int
+	visited := make(map[C.uint]struct{})
+	var walk func(ast C.Z3_ast)
+	walk = func(ast C.Z3_ast) {
+		id := C.Z3_get_ast_id(s.ctx, ast)
+		if _, ok := visited[id]; ok {
+			return
+		}
+		visited[id] = struct{}{}
+		switch C.Z3_get_ast_kind(s.ctx, ast) {
+		case C.Z3_APP_AST:
+			app := C.Z3_to_app(s.ctx, ast)
+			n := int(C.Z3_get_app_num_args(s.ctx, app))
+			decl := C.Z3_get_app_decl(s.ctx, app)
+			if n == 0 && C.Z3_get_decl_kind(s.ctx, decl) == C.Z3_OP_UNINTERPRETED {
+				consts = append(consts, int(id))
+				name := C.GoString(C.Z3_get_symbol_string(s.ctx, C.Z3_get_decl_name(s.ctx, decl)))
+				if m := symbolIndexPattern.FindStringSubmatch(name); m != nil {
+					i, _ := strconv.Atoi(m[1])
+					symbols = append(symbols, i)
+				}
+			}
+			for i := 0; i < n; i++ {
+				walk(C.Z3_get_app_arg(s.ctx, app, C.uint(i)))
+			}
+		case C.Z3_QUANTIFIER_AST:
+			walk(C.Z3_get_quantifier_body(s.ctx, ast))
+		}
+	}
+	walk(ast)
+	return consts, symbols
+}
+
+// relevant returns the constraints that depend on target, either directly or through other constraints,
+// and reports for each symbol whether it is involved in them.
+// Two constraints depend on each other if they share a constant or mention the same symbol.
+// The other constraints are independent of target, so they can be dropped
+// as long as the symbols involved in them keep their values.
+func (s *Z3Solver) relevant(constraints []C.Z3_ast, target C.Z3_ast) ([]C.Z3_ast, []bool) {
+	// The nodes of the union-find are -1 for target, -(i+2) for constraints[i], and
+	// the IDs of constants (which are non-negative). Symbols are identified with
+	// the constants symbolConst(i) that do not exist in Z3.
+	symbolConst := func(i int) int {
+		return -(len(constraints) + 2 + i)
+	}
+	u := make(unionFind)
+	connect := func(node int, ast C.Z3_ast) {
+		consts, symbols := s.constants(ast)
+		for _, c := range consts {
+			u.union(node, c)
+		}
+		for _, i := range symbols {
+			u.union(node, symbolConst(i))
+		}
+	}
+	connect(-1, target)
+	for i, c := range constraints {
+		connect(-(i + 2), c)
+	}
+
+	root := u.find(-1)
+	var group []C.Z3_ast
+	for i, c := range constraints {
+		if u.find(-(i + 2)) == root {
+			group = append(group, c)
+		}
+	}
+	involved := make([]bool, len(s.symbols))
+	for i := range s.symbols {
+		involved[i] = u.find(symbolConst(i)) == root
+	}
+	return group, involved
+}
+
+// queryKey returns the key of the query consisting of constraints.
+// Since ASTs are hash-consed in a Z3 context, structurally identical constraints have the same ID.
+func (s *Z3Solver) queryKey(constraints []C.Z3_ast) string {
+	ids := make([]int, len(constraints))
+	for i, c := range constraints {
+		ids[i] = int(C.Z3_get_ast_id(s.ctx, c))
+	}
+	sort.Ints(ids)
+	var b strings.Builder
+	for i, id := range ids {
+		// Duplicated constraints do not change the query.
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+		fmt.Fprintf(&b, "%d,", id)
+	}
+	return b.String()
+}
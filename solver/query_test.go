@@ -0,0 +1,116 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const queryTestSrc = `package p
+
+func Slice(x, y, z, w int) int {
+	if x > 0 {
+		if y > x {
+			if w > 0 {
+				if z > y {
+					return 1
+				}
+			}
+		}
+	}
+	return 0
+}
+`
+
+// newQueryTestSolver returns the solver of the trace of Slice that takes all the ifs but the last one.
+func newQueryTestSolver(t *testing.T, context *Z3Context, fn *ssa.Function) *Z3Solver {
+	t.Helper()
+	symbols := make([]ssa.Value, len(fn.Params))
+	for i, param := range fn.Params {
+		symbols[i] = param
+	}
+	s, err := CreateZ3Solver(context, symbols, nil, pathTrace(fn, true, true, true, false), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	if n := len(s.Branches()); n != 4 {
+		t.Fatalf("%d branches, want 4", n)
+	}
+	return s
+}
+
+func TestRelevant(t *testing.T) {
+	fn := buildTestPackage(t, queryTestSrc).Func("Slice")
+	context := NewZ3Context()
+	defer context.Close()
+	s := newQueryTestSolver(t, context, fn)
+
+	// z > y depends on y > x directly and on x > 0 through y > x, but not on w > 0.
+	group, involved := s.relevant(s.conds[:3], s.getBranchAST(3, true))
+	if len(group) != 2 || group[0] != s.conds[0] || group[1] != s.conds[1] {
+		t.Errorf("expected the constraints of x > 0 and y > x, actual %d constraints", len(group))
+	}
+	if want := []bool{true, true, true, false}; !reflect.DeepEqual(involved, want) {
+		t.Errorf("expected involved symbols %v, actual %v", want, involved)
+	}
+
+	// w > 0 is independent of the other constraints.
+	group, involved = s.relevant(s.conds[:2], s.getBranchAST(2, true))
+	if len(group) != 0 {
+		t.Errorf("expected no constraints, actual %d constraints", len(group))
+	}
+	if want := []bool{false, false, false, true}; !reflect.DeepEqual(involved, want) {
+		t.Errorf("expected involved symbols %v, actual %v", want, involved)
+	}
+
+	// The solution of the sliced-away symbol is nil, which keeps its value.
+	sols, err := s.Solve(3)
+	checkSolutions(t, "z3", sols, err, func(values []interface{}) bool {
+		if values[0] == nil || values[1] == nil || values[2] == nil || values[3] != nil {
+			return false
+		}
+		x, y, z := values[0].(int), values[1].(int), values[2].(int)
+		return x > 0 && y > x && z > y
+	})
+}
+
+func TestQueryCache(t *testing.T) {
+	fn := buildTestPackage(t, queryTestSrc).Func("Slice")
+	context := NewZ3Context()
+	defer context.Close()
+	s := newQueryTestSolver(t, context, fn)
+
+	sols, err := s.Solve(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(context.cache); n != 1 {
+		t.Fatalf("%d queries are cached, want 1", n)
+	}
+	// The same query hits the cache of the context, whichever solver on the context solves it.
+	for name, s := range map[string]*Z3Solver{"same": s, "another": newQueryTestSolver(t, context, fn)} {
+		cached, err := s.Solve(3)
+		if err != nil {
+			t.Fatalf("%s solver: %v", name, err)
+		}
+		if !reflect.DeepEqual(cached, sols) {
+			t.Errorf("%s solver: expected the cached model %v, actual %v", name, sols, cached)
+		}
+	}
+	if n := len(context.cache); n != 1 {
+		t.Errorf("%d queries are cached, want 1", n)
+	}
+
+	// Another query is not answered by the cache.
+	if _, err := s.Solve(2); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(context.cache); n != 2 {
+		t.Errorf("%d queries are cached, want 2", n)
+	}
+}
@@ -0,0 +1,87 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <stdlib.h>
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"go/types"
+	"unsafe"
+
+	"github.com/ajalab/congo/log"
+)
+
+// leaf is a constant of basic type ty that constitutes the symbol-th symbol.
+type leaf struct {
+	ast    C.Z3_ast
+	ty     *types.Basic
+	symbol int
+}
+
+// readableModel returns a model of constraints on the involved symbols that prefers small absolute values of integers,
+// short strings, and printable characters in this order, or nil if the optimization does not finish in time.
+// The values of symbols in such a model are easier to read in the generated tests.
+func (s *Z3Solver) readableModel(constraints []C.Z3_ast, involved []bool) C.Z3_model {
+	opt := C.Z3_mk_optimize(s.ctx)
+	C.Z3_optimize_inc_ref(s.ctx, opt)
+	defer C.Z3_optimize_dec_ref(s.ctx, opt)
+	params := s.timeoutParams(s.context.Timeout)
+	defer C.Z3_params_dec_ref(s.ctx, params)
+	C.Z3_optimize_set_params(s.ctx, opt, params)
+	for _, c := range constraints {
+		C.Z3_optimize_assert(s.ctx, opt, c)
+	}
+
+	printable := C.Z3_mk_re_star(s.ctx, C.Z3_mk_re_range(s.ctx, z3MakeString(s.ctx, " "), z3MakeString(s.ctx, "~")))
+	var abss, lens, printables []C.Z3_ast
+	for _, leaf := range s.leaves {
+		if !involved[leaf.symbol] {
+			continue
+		}
+		info := leaf.ty.Info()
+		switch {
+		case info&types.IsInteger > 0:
+			x := leaf.ast
+			if info&types.IsUnsigned == 0 {
+				zero := C.Z3_mk_int(s.ctx, 0, C.Z3_get_sort(s.ctx, x))
+				x = C.Z3_mk_ite(s.ctx, C.Z3_mk_bvslt(s.ctx, x, zero), C.Z3_mk_bvneg(s.ctx, x), x)
+			}
+			abss = append(abss, x)
+		case info&types.IsString > 0:
+			lens = append(lens, C.Z3_mk_seq_length(s.ctx, leaf.ast))
+			printables = append(printables, C.Z3_mk_seq_in_re(s.ctx, leaf.ast, printable))
+		}
+	}
+	if len(abss) == 0 && len(lens) == 0 {
+		return nil
+	}
+
+	// The objectives are prioritized lexicographically in the order they are added.
+	// Minimizing each integer is faster than minimizing their sum.
+	for _, x := range abss {
+		C.Z3_optimize_minimize(s.ctx, opt, x)
+	}
+	if len(lens) > 0 {
+		C.Z3_optimize_minimize(s.ctx, opt, C.Z3_mk_add(s.ctx, C.uint(len(lens)), &lens[0]))
+	}
+	weight := C.CString("1")
+	defer C.free(unsafe.Pointer(weight))
+	id := z3MkStringSymbol(s.ctx, "printable")
+	for _, p := range printables {
+		C.Z3_optimize_assert_soft(s.ctx, opt, p, weight, id)
+	}
+
+	if C.Z3_optimize_check(s.ctx, opt, 0, nil) != C.Z3_L_TRUE {
+		log.Debug.Printf("no readable model was found: %s", C.GoString(C.Z3_optimize_get_reason_unknown(s.ctx, opt)))
+		return nil
+	}
+	m := C.Z3_optimize_get_model(s.ctx, opt)
+	C.Z3_model_inc_ref(s.ctx, m)
+	return m
+}
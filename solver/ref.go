@@ -13,3 +13,29 @@ type ref struct {
 func (v *ref) Type() types.Type {
 	return v.Value.Type().(*types.Pointer).Elem()
 }
+
+// field is a pseudo value that represents the index-th field of a struct value.
+type field struct {
+	ssa.Value
+	index int
+}
+
+func (v *field) Type() types.Type {
+	return v.Value.Type().Underlying().(*types.Struct).Field(v.index).Type()
+}
+
+// clone is a pseudo value that represents a copy of a struct value
+// whose fields may be updated independently of the original one.
+type clone struct {
+	ssa.Value
+}
+
+// component is a pseudo value that represents the index-th component of a tuple.
+type component struct {
+	ssa.Value
+	index int
+}
+
+func (v *component) Type() types.Type {
+	return v.Value.Type().(*types.Tuple).At(v.index).Type()
+}
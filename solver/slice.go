@@ -0,0 +1,366 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <stdlib.h>
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"go/types"
+	"strconv"
+
+	"github.com/ajalab/congo/log"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ssa"
+)
+
+// maxSymbolicSliceLen is the maximum length of symbolic slices.
+// It keeps the generated test cases small.
+const maxSymbolicSliceLen = 16
+
+// sliceAST is a symbolic representation of a slice or an array.
+// The i-th element is represented by array[offset + i].
+// array is nil if the element type is not supported by the solver.
+type sliceAST struct {
+	array  C.Z3_ast
+	offset C.Z3_ast
+	len    C.Z3_ast
+	cap    C.Z3_ast
+}
+
+func z3MakeIntSort(ctx C.Z3_context) C.Z3_sort {
+	return C.Z3_mk_bv_sort(ctx, strconv.IntSize)
+}
+
+func z3MakeIntNumeral(ctx C.Z3_context, n int) C.Z3_ast {
+	return C.Z3_mk_int64(ctx, C.int64_t(n), z3MakeIntSort(ctx))
+}
+
+// z3MakeIntCast converts an integer x of type ty to an integer of type int.
+func z3MakeIntCast(ctx C.Z3_context, x C.Z3_ast, ty types.Type) C.Z3_ast {
+	basicTy, ok := ty.Underlying().(*types.Basic)
+	return z3MakeResize(ctx, x, strconv.IntSize, ok && basicTy.Info()&types.IsUnsigned > 0)
+}
+
+// z3MakeZero returns the zero value of ty.
+func z3MakeZero(ctx C.Z3_context, ty *types.Basic) C.Z3_ast {
+	info := ty.Info()
+	switch {
+	case info&types.IsBoolean > 0:
+		return C.Z3_mk_false(ctx)
+	case info&types.IsInteger > 0:
+		return C.Z3_mk_int(ctx, 0, newBasicSort(ctx, ty))
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_zero(ctx, newBasicSort(ctx, ty), false)
+	case info&types.IsString > 0:
+		return z3MakeString(ctx, "")
+	}
+	log.Error.Fatalf("z3MakeZero: unsupported basic type: %v", ty)
+	panic("unimplemented")
+}
+
+// elemType returns the element type of ty if elements of ty can be symbolic.
+func elemType(ty types.Type) (*types.Basic, bool) {
+	var elem types.Type
+	switch ty := ty.Underlying().(type) {
+	case *types.Slice:
+		elem = ty.Elem()
+	case *types.Array:
+		elem = ty.Elem()
+	default:
+		return nil, false
+	}
+	basicTy, ok := elem.Underlying().(*types.Basic)
+	if !ok {
+		return nil, false
+	}
+	if !isSupportedBasic(basicTy) {
+		return nil, false
+	}
+	return basicTy, true
+}
+
+// loadSliceSymbol loads a symbolic slice or array.
+// The length of a slice is bounded by maxSymbolicSliceLen.
+func (s *Z3Solver) loadSliceSymbol(symbol ssa.Value, name string) {
+	var l C.Z3_ast
+	n := maxSymbolicSliceLen
+	switch ty := symbol.Type().Underlying().(type) {
+	case *types.Slice:
+		l = C.Z3_mk_const(s.ctx, z3MkStringSymbol(s.ctx, "len("+name+")"), z3MakeIntSort(s.ctx))
+		args := []C.Z3_ast{
+			C.Z3_mk_bvsge(s.ctx, l, z3MakeIntNumeral(s.ctx, 0)),
+			C.Z3_mk_bvsle(s.ctx, l, z3MakeIntNumeral(s.ctx, maxSymbolicSliceLen)),
+		}
+		s.axioms = append(s.axioms, C.Z3_mk_and(s.ctx, 2, &args[0]))
+		s.leaves = append(s.leaves, leaf{ast: l, ty: types.Typ[types.Int]})
+	case *types.Array:
+		l = z3MakeIntNumeral(s.ctx, int(ty.Len()))
+		n = int(ty.Len())
+	}
+	sl := sliceAST{
+		offset: z3MakeIntNumeral(s.ctx, 0),
+		len:    l,
+		cap:    l,
+	}
+	if elemTy, ok := elemType(symbol.Type()); ok {
+		sort := C.Z3_mk_array_sort(s.ctx, z3MakeIntSort(s.ctx), newBasicSort(s.ctx, elemTy))
+		sl.array = C.Z3_mk_const(s.ctx, z3MkStringSymbol(s.ctx, name), sort)
+		for i := 0; i < n; i++ {
+			s.leaves = append(s.leaves, leaf{ast: C.Z3_mk_select(s.ctx, sl.array, z3MakeIntNumeral(s.ctx, i)), ty: elemTy})
+		}
+	}
+	s.slices[symbol] = sl
+}
+
+// newZeroSlice returns a slice whose elements are zero values.
+func (s *Z3Solver) newZeroSlice(ty types.Type, l, c C.Z3_ast) sliceAST {
+	sl := sliceAST{
+		offset: z3MakeIntNumeral(s.ctx, 0),
+		len:    l,
+		cap:    c,
+	}
+	if elemTy, ok := elemType(ty); ok {
+		sl.array = C.Z3_mk_const_array(s.ctx, z3MakeIntSort(s.ctx), z3MakeZero(s.ctx, elemTy))
+	}
+	return sl
+}
+
+// sliceOf returns the symbolic representation of a slice or an array x.
+// If x is a pointer to an array, the representation of the referenced array is returned.
+func (s *Z3Solver) sliceOf(x ssa.Value) (sliceAST, bool) {
+	if _, ok := x.Type().Underlying().(*types.Pointer); ok {
+		ref, ok := s.refs[x]
+		if !ok {
+			return sliceAST{}, false
+		}
+		x = ref
+	}
+	sl, ok := s.slices[x]
+	return sl, ok
+}
+
+// boundsCond returns the condition that the bounds check of v succeeds.
+// It returns nil if the operands of v are not symbolic.
+func (s *Z3Solver) boundsCond(v ssa.Value) C.Z3_ast {
+	switch v := v.(type) {
+	case *ssa.IndexAddr:
+		return s.indexBoundsCond(v.X, v.Index)
+	case *ssa.Index:
+		return s.indexBoundsCond(v.X, v.Index)
+	case *ssa.Lookup:
+		// Lookups of maps never fail.
+		if isString(v.X.Type()) {
+			return s.stringBoundsCond(v)
+		}
+	case *ssa.Slice:
+		if isString(v.X.Type()) {
+			return s.stringBoundsCond(v)
+		}
+		sl, ok := s.sliceOf(v.X)
+		if !ok {
+			return nil
+		}
+		low, high, max, ok := s.sliceBounds(v, sl)
+		if !ok {
+			return nil
+		}
+		args := []C.Z3_ast{
+			C.Z3_mk_bvule(s.ctx, low, high),
+			C.Z3_mk_bvule(s.ctx, high, max),
+			C.Z3_mk_bvule(s.ctx, max, sl.cap),
+		}
+		return C.Z3_mk_and(s.ctx, 3, &args[0])
+	}
+	return nil
+}
+
+func (s *Z3Solver) indexBoundsCond(x, index ssa.Value) C.Z3_ast {
+	sl, ok := s.sliceOf(x)
+	if !ok {
+		return nil
+	}
+	i := s.get(index)
+	if i == nil {
+		return nil
+	}
+	// Negative indices are regarded as large unsigned integers.
+	return C.Z3_mk_bvult(s.ctx, i, sl.len)
+}
+
+// sliceBounds returns the (low, high, max) indices of the slice operation instr.
+func (s *Z3Solver) sliceBounds(instr *ssa.Slice, sl sliceAST) (C.Z3_ast, C.Z3_ast, C.Z3_ast, bool) {
+	bound := func(v ssa.Value, def C.Z3_ast) C.Z3_ast {
+		if v == nil {
+			return def
+		}
+		ast := s.get(v)
+		if ast == nil {
+			return nil
+		}
+		return z3MakeIntCast(s.ctx, ast, v.Type())
+	}
+	low := bound(instr.Low, z3MakeIntNumeral(s.ctx, 0))
+	high := bound(instr.High, sl.len)
+	max := bound(instr.Max, sl.cap)
+	return low, high, max, low != nil && high != nil && max != nil
+}
+
+// addBoundsBranch appends a branch of a successful bounds check if the condition is not trivial.
+func (s *Z3Solver) addBoundsBranch(instr ssa.Instruction, cond C.Z3_ast) {
+	if C.Z3_get_bool_value(s.ctx, C.Z3_simplify(s.ctx, cond)) != C.Z3_L_UNDEF {
+		return
+	}
+	s.addBranch(&BranchBounds{
+		instr:   instr,
+		success: true,
+	}, cond)
+}
+
+// indexAddr loads the address of an element of a slice or an array.
+func (s *Z3Solver) indexAddr(instr *ssa.IndexAddr) {
+	sl, ok := s.sliceOf(instr.X)
+	if !ok {
+		return
+	}
+	cond := s.indexBoundsCond(instr.X, instr.Index)
+	if cond == nil {
+		return
+	}
+	s.checkNonNull(instr, instr.X)
+	s.addBoundsBranch(instr, cond)
+	if sl.array != nil {
+		i := C.Z3_mk_bvadd(s.ctx, sl.offset, s.get(instr.Index))
+		ref := &ref{instr}
+		s.asts[ref] = C.Z3_mk_select(s.ctx, sl.array, i)
+		s.refs[instr] = ref
+	}
+}
+
+// index loads an element of an array.
+func (s *Z3Solver) index(instr *ssa.Index) {
+	sl, ok := s.sliceOf(instr.X)
+	if !ok {
+		return
+	}
+	cond := s.indexBoundsCond(instr.X, instr.Index)
+	if cond == nil {
+		return
+	}
+	s.addBoundsBranch(instr, cond)
+	if sl.array != nil {
+		i := C.Z3_mk_bvadd(s.ctx, sl.offset, s.get(instr.Index))
+		s.asts[instr] = C.Z3_mk_select(s.ctx, sl.array, i)
+	}
+}
+
+// slice loads a slice operation on a slice, an array, or a string.
+func (s *Z3Solver) slice(instr *ssa.Slice) {
+	if isString(instr.X.Type()) {
+		s.stringSlice(instr)
+		return
+	}
+	sl, ok := s.sliceOf(instr.X)
+	if !ok {
+		return
+	}
+	low, high, max, ok := s.sliceBounds(instr, sl)
+	if !ok {
+		return
+	}
+	s.checkNonNull(instr, instr.X)
+	s.addBoundsBranch(instr, s.boundsCond(instr))
+	s.slices[instr] = sliceAST{
+		array:  sl.array,
+		offset: C.Z3_mk_bvadd(s.ctx, sl.offset, low),
+		len:    C.Z3_mk_bvsub(s.ctx, high, low),
+		cap:    C.Z3_mk_bvsub(s.ctx, max, low),
+	}
+}
+
+// storeElem updates the element referenced by addr with val.
+func (s *Z3Solver) storeElem(addr *ssa.IndexAddr, val ssa.Value) {
+	sl, ok := s.sliceOf(addr.X)
+	if !ok || sl.array == nil {
+		return
+	}
+	i := s.get(addr.Index)
+	v := s.get(val)
+	if i == nil || v == nil {
+		return
+	}
+	sl.array = C.Z3_mk_store(s.ctx, sl.array, C.Z3_mk_bvadd(s.ctx, sl.offset, i), v)
+	if _, ok := addr.X.Type().Underlying().(*types.Pointer); ok {
+		c := &clone{s.refs[addr.X]}
+		s.slices[c] = sl
+		s.store(addr.X, c)
+		return
+	}
+	s.slices[addr.X] = sl
+}
+
+// alloc loads an allocation of an array.
+func (s *Z3Solver) alloc(instr *ssa.Alloc) {
+	ty := instr.Type().(*types.Pointer).Elem()
+	arrayTy, ok := ty.Underlying().(*types.Array)
+	if !ok {
+		return
+	}
+	l := z3MakeIntNumeral(s.ctx, int(arrayTy.Len()))
+	ref := &ref{instr}
+	s.slices[ref] = s.newZeroSlice(ty, l, l)
+	s.refs[instr] = ref
+}
+
+// makeSlice loads a slice creation by make.
+func (s *Z3Solver) makeSlice(instr *ssa.MakeSlice) {
+	l, c := s.get(instr.Len), s.get(instr.Cap)
+	if l == nil || c == nil {
+		return
+	}
+	l = z3MakeIntCast(s.ctx, l, instr.Len.Type())
+	c = z3MakeIntCast(s.ctx, c, instr.Cap.Type())
+	s.slices[instr] = s.newZeroSlice(instr.Type(), l, c)
+}
+
+func (s *Z3Solver) getSliceSolution(m C.Z3_model, ty types.Type, sl sliceAST) (Solution, error) {
+	var l C.Z3_ast
+	if ok := C.Z3_model_eval(s.ctx, m, sl.len, C.bool(true), &l); !C.bool(ok) {
+		return nil, errors.New("failed to extract the length of a slice from the model")
+	}
+	var n C.int64_t
+	if ok := bool(C.Z3_get_numeral_int64(s.ctx, l, &n)); !ok {
+		return nil, errors.New("Z3_get_numeral_int64: could not get an int64 representation of the length")
+	}
+	// The length of an array is fixed, so that only the length of a slice is bounded.
+	if _, isSlice := ty.Underlying().(*types.Slice); n < 0 || isSlice && n > maxSymbolicSliceLen {
+		return nil, errors.Errorf("invalid length of a slice: %d", n)
+	}
+
+	elemTy, ok := elemType(ty)
+	sols := make([]Solution, n)
+	for i := range sols {
+		if !ok || sl.array == nil {
+			// Slices and arrays share the Elem method.
+			sols[i] = Indefinite{ty: ty.Underlying().(interface{ Elem() types.Type }).Elem()}
+			continue
+		}
+		var elem C.Z3_ast
+		idx := C.Z3_mk_bvadd(s.ctx, sl.offset, z3MakeIntNumeral(s.ctx, i))
+		if ok := C.Z3_model_eval(s.ctx, m, C.Z3_mk_select(s.ctx, sl.array, idx), C.bool(true), &elem); !C.bool(ok) {
+			return nil, errors.Errorf("failed to extract the element %d of a slice from the model", i)
+		}
+		sol, err := s.getBasicSolution(elem, elemTy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get a value for the element %d", i)
+		}
+		sols[i] = sol
+	}
+	return Definite{ty: ty, value: sols}, nil
+}
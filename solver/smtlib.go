@@ -0,0 +1,829 @@
+package solver
+
+import (
+	"bufio"
+	"fmt"
+	"go/types"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ajalab/congo/log"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ssa"
+)
+
+// defaultSMTLibCommand is the default command line of the solver process of the smtlib backend.
+const defaultSMTLibCommand = "z3 -in -smt2"
+
+// smtLibPrelude is the commands that precede the queries.
+const smtLibPrelude = "(set-option :produce-models true)\n(set-logic ALL)\n"
+
+func init() {
+	registerBackend("smtlib", func(command string) (Backend, error) {
+		return newSMTLibBackend(command)
+	}, smtLibSupports, false)
+}
+
+// smtLibSupports reports whether symbols of type ty can be represented by the smtlib backend,
+// i.e., ty is a boolean, number, or string type, or a struct type whose fields are of such types.
+func smtLibSupports(ty types.Type) bool {
+	switch ty := ty.Underlying().(type) {
+	case *types.Basic:
+		return isSupportedBasic(ty)
+	case *types.Struct:
+		for i := 0; i < ty.NumFields(); i++ {
+			if !smtLibSupports(ty.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// smtLibBackend is a backend that talks to a solver process (e.g., z3, cvc5, or bitwuzla)
+// in SMT-LIB2 over its standard input and output.
+// It does not depend on cgo, but supports only booleans, numbers, strings, and structs of them as symbols.
+// The process is started on the first query and restarted after it is killed
+// because of the time limit or an interruption.
+type smtLibBackend struct {
+	command  []string
+	readable bool
+	overflow bool
+	loops    Loops
+
+	// query serializes the queries, which own stdin and stdout while they are solved.
+	query  sync.Mutex
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+
+	// mu guards the fields below, which are also accessed by Interrupt and the timer of the time limit.
+	mu      sync.Mutex
+	timeout time.Duration
+	cmd     *exec.Cmd
+	// running reports whether a query is being solved.
+	running bool
+	// aborted is the reason why the process was killed while solving a query.
+	aborted string
+}
+
+func newSMTLibBackend(command string) (*smtLibBackend, error) {
+	if command == "" {
+		command = defaultSMTLibCommand
+	}
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty solver command")
+	}
+	return &smtLibBackend{command: args}, nil
+}
+
+// NewSolver returns a new solver for the trace instrs.
+func (b *smtLibBackend) NewSolver(symbols []ssa.Value, concreteTypes []types.Type, instrs []ssa.Instruction, isComplete bool) (Solver, error) {
+	s := &smtLibSolver{
+		backend:   b,
+		terms:     make(map[ssa.Value]string),
+		fields:    make(map[ssa.Value][]ssa.Value),
+		tuples:    make(map[ssa.Value][]ssa.Value),
+		refs:      make(map[ssa.Value]ssa.Value),
+		negations: make(map[int]string),
+	}
+	if err := s.loadSymbols(symbols); err != nil {
+		return nil, errors.Wrap(err, "failed to load symbols")
+	}
+	if err := s.loadTrace(instrs, isComplete); err != nil {
+		return nil, errors.Wrap(err, "failed to load trace")
+	}
+	return s, nil
+}
+
+// SetTimeout sets the time limit of each query.
+func (b *smtLibBackend) SetTimeout(timeout time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timeout = timeout
+}
+
+// SetOverflow makes the solvers record the conditions of overflows.
+func (b *smtLibBackend) SetOverflow(overflow bool) {
+	b.overflow = overflow
+}
+
+// SetLoops makes the solvers summarize the conditions of the counting loops returned by loops.
+func (b *smtLibBackend) SetLoops(loops Loops) {
+	b.loops = loops
+}
+
+// SetReadable makes the solvers prefer readable models, which are found by tightening the bounds of
+// the absolute values of integers and the lengths of strings by binary search.
+func (b *smtLibBackend) SetReadable(readable bool) {
+	b.readable = readable
+}
+
+// Interrupt kills the process if a query is being solved.
+func (b *smtLibBackend) Interrupt() {
+	b.kill("canceled")
+}
+
+// Close terminates the process.
+func (b *smtLibBackend) Close() {
+	b.query.Lock()
+	defer b.query.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cmd == nil {
+		return
+	}
+	fmt.Fprintln(b.stdin, "(exit)")
+	b.stop()
+}
+
+// start starts the process. It must be called with mu held.
+func (b *smtLibBackend) start() error {
+	cmd := exec.Command(b.command[0], b.command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.Wrapf(err, "failed to start the solver %s", b.command[0])
+	}
+	b.cmd, b.stdin, b.stdout = cmd, stdin, bufio.NewReader(stdout)
+	_, err = io.WriteString(b.stdin, "(set-option :print-success false)\n"+smtLibPrelude)
+	return err
+}
+
+// stop waits for the process to exit after closing its standard input,
+// so that it is started again on the next query. It must be called with mu held.
+func (b *smtLibBackend) stop() {
+	b.stdin.Close()
+	b.cmd.Wait()
+	b.cmd = nil
+}
+
+// kill kills the process for reason if a query is being solved.
+func (b *smtLibBackend) kill(reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.running && b.cmd != nil && b.aborted == "" {
+		b.aborted = reason
+		b.cmd.Process.Kill()
+	}
+}
+
+// check checks the satisfiability of the assertions in script, which are made in a new scope,
+// and returns the values of terms in the model if they are satisfiable.
+// If the backend prefers readable models, the objectives are minimized.
+func (b *smtLibBackend) check(script string, terms []string, objectives []smtLibObjective) ([]sexp, error) {
+	b.query.Lock()
+	defer b.query.Unlock()
+
+	b.mu.Lock()
+	if b.cmd == nil {
+		if err := b.start(); err != nil {
+			b.mu.Unlock()
+			return nil, err
+		}
+	}
+	b.running = true
+	if b.timeout > 0 {
+		timer := time.AfterFunc(b.timeout, func() { b.kill("timeout") })
+		defer timer.Stop()
+	}
+	b.mu.Unlock()
+
+	values, err := b.exchange(script, terms, objectives)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.running = false
+	if reason := b.aborted; reason != "" {
+		// The process has been killed, so it is started again on the next query.
+		b.stop()
+		b.aborted = ""
+		if err == nil && values != nil {
+			// The process was killed while the model was tightened.
+			return values, nil
+		}
+		return nil, UnknownError{Reason: reason}
+	}
+	switch err.(type) {
+	case nil, UnsatError, UnknownError:
+	default:
+		// The responses may be out of sync after an error, so the process is started again.
+		b.stop()
+	}
+	return values, err
+}
+
+func (b *smtLibBackend) exchange(script string, terms []string, objectives []smtLibObjective) ([]sexp, error) {
+	if _, err := io.WriteString(b.stdin, "(push 1)\n"+script); err != nil {
+		return nil, err
+	}
+	scopes := 1
+	defer func() { fmt.Fprintf(b.stdin, "(pop %d)\n", scopes) }()
+	result, err := b.checkSat()
+	if err != nil {
+		return nil, err
+	}
+	switch result {
+	case "sat":
+	case "unsat":
+		return nil, UnsatError{}
+	case "unknown":
+		if _, err := io.WriteString(b.stdin, "(get-info :reason-unknown)\n"); err != nil {
+			return nil, err
+		}
+		reason := "unknown"
+		if info, err := readSexp(b.stdout); err == nil {
+			if l, ok := info.([]sexp); ok && len(l) == 2 {
+				reason = strings.Trim(fmt.Sprint(l[1]), `"`)
+			}
+		}
+		return nil, UnknownError{Reason: reason}
+	default:
+		return nil, errors.Errorf("solver error: %s", result)
+	}
+
+	values, err := b.getValues(terms)
+	if err != nil || !b.readable || len(objectives) == 0 {
+		return values, err
+	}
+	// The values found so far are returned if the tightening fails (e.g., by the time limit).
+	n, err := b.tighten(objectives)
+	scopes += n
+	if err != nil {
+		log.Debug.Printf("no readable model was found: %v", err)
+		return values, nil
+	}
+	if result, err := b.checkSat(); err != nil || result != "sat" {
+		return values, nil
+	}
+	if readable, err := b.getValues(terms); err == nil {
+		values = readable
+	}
+	return values, nil
+}
+
+// checkSat checks the satisfiability of the current assertions and returns the result.
+func (b *smtLibBackend) checkSat() (string, error) {
+	if _, err := io.WriteString(b.stdin, "(check-sat)\n"); err != nil {
+		return "", err
+	}
+	result, err := readSexp(b.stdout)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the result")
+	}
+	return fmt.Sprint(result), nil
+}
+
+// getValues returns the values of terms in the model of the last check.
+func (b *smtLibBackend) getValues(terms []string) ([]sexp, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if _, err := io.WriteString(b.stdin, "(get-value ("+strings.Join(terms, " ")+"))\n"); err != nil {
+		return nil, err
+	}
+	model, err := readSexp(b.stdout)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the model")
+	}
+	pairs, ok := model.([]sexp)
+	if !ok || len(pairs) != len(terms) {
+		return nil, errors.Errorf("solver error: %v", model)
+	}
+	values := make([]sexp, len(pairs))
+	for i, pair := range pairs {
+		l, ok := pair.([]sexp)
+		if !ok || len(l) != 2 {
+			return nil, errors.Errorf("invalid value: %v", pair)
+		}
+		values[i] = l[1]
+	}
+	return values, nil
+}
+
+// smtLibObjective is an objective for readable models.
+type smtLibObjective struct {
+	// term is either a bit-vector of width bits or an integer (if width is zero) to be minimized,
+	// or an assertion to be satisfied if possible (if soft is true).
+	term  string
+	width uint
+	soft  bool
+}
+
+// bound returns the assertion that the term of o is at most n.
+func (o smtLibObjective) bound(n uint64) string {
+	if o.width == 0 {
+		return fmt.Sprintf("(<= %s %d)", o.term, n)
+	}
+	return fmt.Sprintf("(bvule %s (_ bv%d %d))", o.term, n, o.width)
+}
+
+// tighten asserts the bounds of objectives that are minimized one by one by binary search,
+// and returns the number of the scopes it has pushed.
+// It must be called right after a satisfiable check.
+func (b *smtLibBackend) tighten(objectives []smtLibObjective) (int, error) {
+	scopes := 0
+	sat := true
+	for _, o := range objectives {
+		if !sat {
+			// A model is needed to get the value of the next objective.
+			result, err := b.checkSat()
+			if err != nil {
+				return scopes, err
+			}
+			if result != "sat" {
+				return scopes, errors.Errorf("the bounds are not satisfiable: %s", result)
+			}
+		}
+		if o.soft {
+			if _, err := io.WriteString(b.stdin, "(push 1)\n(assert "+o.term+")\n"); err != nil {
+				return scopes, err
+			}
+			result, err := b.checkSat()
+			if err != nil {
+				return scopes, err
+			}
+			if sat = result == "sat"; sat {
+				scopes++
+			} else if _, err := io.WriteString(b.stdin, "(pop 1)\n"); err != nil {
+				return scopes, err
+			}
+			continue
+		}
+
+		current, err := b.objectiveValue(o)
+		if err != nil {
+			return scopes, err
+		}
+		var lo uint64
+		sat = true
+		for lo < current {
+			mid := lo + (current-lo)/2
+			if _, err := io.WriteString(b.stdin, "(push 1)\n(assert "+o.bound(mid)+")\n"); err != nil {
+				return scopes, err
+			}
+			result, err := b.checkSat()
+			if err != nil {
+				return scopes, err
+			}
+			if result == "sat" {
+				if current, err = b.objectiveValue(o); err != nil {
+					return scopes, err
+				}
+			} else {
+				lo = mid + 1
+			}
+			if _, err := io.WriteString(b.stdin, "(pop 1)\n"); err != nil {
+				return scopes, err
+			}
+			sat = false
+		}
+		if _, err := io.WriteString(b.stdin, "(push 1)\n(assert "+o.bound(current)+")\n"); err != nil {
+			return scopes, err
+		}
+		scopes++
+		sat = false
+	}
+	return scopes, nil
+}
+
+// objectiveValue returns the value of the term of o in the model of the last check.
+func (b *smtLibBackend) objectiveValue(o smtLibObjective) (uint64, error) {
+	values, err := b.getValues([]string{o.term})
+	if err != nil {
+		return 0, err
+	}
+	if o.width == 0 {
+		return strconv.ParseUint(fmt.Sprint(values[0]), 10, 64)
+	}
+	u, _, err := parseSMTLibBits(values[0])
+	return u, err
+}
+
+// sexp is an S-expression, which is either an atom (string) or a list ([]sexp).
+// String literals are kept quoted.
+type sexp interface{}
+
+// readSexp reads an S-expression from r.
+func readSexp(r *bufio.Reader) (sexp, error) {
+	var stack [][]sexp
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		var atom string
+		switch {
+		case c == ';':
+			if _, err := r.ReadString('\n'); err != nil {
+				return nil, err
+			}
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		case c == '(':
+			stack = append(stack, []sexp{})
+			continue
+		case c == ')':
+			if len(stack) == 0 {
+				return nil, errors.New("unbalanced parentheses")
+			}
+			l := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return l, nil
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], l)
+			continue
+		case c == '"' || c == '|':
+			// A string literal, where "" is an escaped quote, or a quoted symbol.
+			b := []byte{c}
+			for {
+				d, err := r.ReadByte()
+				if err != nil {
+					return nil, err
+				}
+				b = append(b, d)
+				if d != c {
+					continue
+				}
+				if next, err := r.Peek(1); c == '"' && err == nil && next[0] == '"' {
+					r.ReadByte()
+					b = append(b, '"')
+					continue
+				}
+				break
+			}
+			atom = string(b)
+		default:
+			b := []byte{c}
+			for {
+				next, err := r.Peek(1)
+				if err != nil || strings.IndexByte(" \t\r\n()\";", next[0]) >= 0 {
+					break
+				}
+				r.ReadByte()
+				b = append(b, next[0])
+			}
+			atom = string(b)
+		}
+		if len(stack) == 0 {
+			return atom, nil
+		}
+		stack[len(stack)-1] = append(stack[len(stack)-1], atom)
+	}
+}
+
+// smtLibString returns the SMT-LIB2 string literal of a Go string.
+// Each byte is a character of the literal. Characters other than printable ASCII ones are escaped.
+func smtLibString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			b.WriteString(`""`)
+		case c == '\\' || c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&b, `\u{%x}`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// parseSMTLibString returns the Go string of an SMT-LIB2 string literal.
+// The characters must be bytes.
+func parseSMTLibString(lit string) (string, error) {
+	if len(lit) < 2 || lit[0] != '"' || lit[len(lit)-1] != '"' {
+		return "", errors.Errorf("invalid string literal: %s", lit)
+	}
+	lit = strings.Replace(lit[1:len(lit)-1], `""`, `"`, -1)
+	var b []byte
+	for i := 0; i < len(lit); i++ {
+		if lit[i] != '\\' || i+1 >= len(lit) || lit[i+1] != 'u' {
+			b = append(b, lit[i])
+			continue
+		}
+		// \ud₃d₂d₁d₀ or \u{d₀} ... \u{d₄d₃d₂d₁d₀}
+		var hex string
+		j := i + 2
+		if j < len(lit) && lit[j] == '{' {
+			end := strings.IndexByte(lit[j:], '}')
+			if end < 0 {
+				b = append(b, lit[i])
+				continue
+			}
+			hex, j = lit[j+1:j+end], j+end+1
+		} else if j+4 <= len(lit) {
+			hex, j = lit[j:j+4], j+4
+		}
+		code, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			b = append(b, lit[i])
+			continue
+		}
+		if code > 0xff {
+			return "", errors.Errorf("invalid character in a string: %#x", code)
+		}
+		b = append(b, byte(code))
+		i = j - 1
+	}
+	return string(b), nil
+}
+
+// parseSMTLibBits returns the value and the width of a bit-vector literal (#b..., #x..., or (_ bvN w)).
+func parseSMTLibBits(v sexp) (uint64, uint, error) {
+	switch v := v.(type) {
+	case string:
+		var base, digitBits int
+		switch {
+		case strings.HasPrefix(v, "#b"):
+			base, digitBits = 2, 1
+		case strings.HasPrefix(v, "#x"):
+			base, digitBits = 16, 4
+		default:
+			return 0, 0, errors.Errorf("invalid bit-vector: %s", v)
+		}
+		digits := v[2:]
+		if len(digits)*digitBits > 64 {
+			return 0, 0, errors.Errorf("too wide bit-vector: %s", v)
+		}
+		u, err := strconv.ParseUint(digits, base, 64)
+		return u, uint(len(digits) * digitBits), err
+	case []sexp:
+		if len(v) == 3 && v[0] == "_" {
+			if s, ok := v[1].(string); ok && strings.HasPrefix(s, "bv") {
+				u, err := strconv.ParseUint(s[2:], 10, 64)
+				if err != nil {
+					return 0, 0, err
+				}
+				w, err := strconv.ParseUint(fmt.Sprint(v[2]), 10, 32)
+				return u, uint(w), err
+			}
+		}
+	}
+	return 0, 0, errors.Errorf("invalid bit-vector: %v", v)
+}
+
+// parseSMTLibFloat returns the value of a floating-point literal,
+// which is either (fp sign exponent significand) or (_ +zero eb sb) and the like.
+func parseSMTLibFloat(v sexp) (float64, error) {
+	l, ok := v.([]sexp)
+	if !ok || len(l) == 0 {
+		return 0, errors.Errorf("invalid floating-point number: %v", v)
+	}
+	switch l[0] {
+	case "_":
+		if len(l) != 4 {
+			break
+		}
+		switch l[1] {
+		case "+zero":
+			return 0, nil
+		case "-zero":
+			return math.Copysign(0, -1), nil
+		case "+oo":
+			return math.Inf(1), nil
+		case "-oo":
+			return math.Inf(-1), nil
+		case "NaN":
+			return math.NaN(), nil
+		}
+	case "fp":
+		if len(l) != 4 {
+			break
+		}
+		var bits uint64
+		var width uint
+		for _, part := range l[1:] {
+			u, w, err := parseSMTLibBits(part)
+			if err != nil {
+				return 0, err
+			}
+			bits, width = bits<<w|u, width+w
+		}
+		switch width {
+		case 32:
+			return float64(math.Float32frombits(uint32(bits))), nil
+		case 64:
+			return math.Float64frombits(bits), nil
+		}
+	}
+	return 0, errors.Errorf("invalid floating-point number: %v", v)
+}
+
+// smtLibBasicSolution returns a solution of basic type ty from the value in a model.
+func smtLibBasicSolution(v sexp, ty *types.Basic) (Solution, error) {
+	info := ty.Info()
+	switch {
+	case info&types.IsBoolean > 0:
+		switch v {
+		case "true":
+			return Definite{ty: ty, value: true}, nil
+		case "false":
+			return Definite{ty: ty, value: false}, nil
+		}
+	case info&types.IsInteger > 0:
+		u, _, err := parseSMTLibBits(v)
+		if err != nil {
+			return nil, err
+		}
+		return integerSolution(u, ty)
+	case info&types.IsFloat > 0:
+		f, err := parseSMTLibFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		if ty.Kind() == types.Float32 {
+			return Definite{ty: ty, value: float32(f)}, nil
+		}
+		return Definite{ty: ty, value: f}, nil
+	case info&types.IsString > 0:
+		if lit, ok := v.(string); ok {
+			str, err := parseSMTLibString(lit)
+			if err != nil {
+				return nil, err
+			}
+			return Definite{ty: ty, value: str}, nil
+		}
+	}
+	return nil, errors.Errorf("invalid value of type %v: %v", ty, v)
+}
+
+// smtLibSolver is a solver of the smtlib backend.
+// The path condition is kept as SMT-LIB2 terms, which are sent to the solver process on each query.
+type smtLibSolver struct {
+	backend *smtLibBackend
+	terms   map[ssa.Value]string
+	fields  map[ssa.Value][]ssa.Value
+	tuples  map[ssa.Value][]ssa.Value
+	// refs maps addresses to the values stored there.
+	refs    map[ssa.Value]ssa.Value
+	symbols []ssa.Value
+	// decls and axioms are the declarations of the constants and the assertions on them.
+	decls     []string
+	axioms    []string
+	branches  []Branch
+	positions []int
+	conds     []string
+	// overflows are the instructions that may overflow, which are loaded
+	// at overflowPositions of the trace and overflow under overflowConds.
+	overflows         []ssa.Instruction
+	overflowPositions []int
+	overflowConds     []string
+	// negations maps the indices of the branches whose negations are not
+	// the negations of their conditions (see BranchLoop) to the negations.
+	negations map[int]string
+
+	// pos is the index of the instruction being loaded in the trace.
+	pos int
+}
+
+// Branches returns the slice of branches.
+func (s *smtLibSolver) Branches() []Branch {
+	return s.branches
+}
+
+// Positions returns the indices of the instructions in the trace at which the branches were taken.
+func (s *smtLibSolver) Positions() []int {
+	return s.positions
+}
+
+// Close does nothing since the solver has no resource of its own.
+func (s *smtLibSolver) Close() {}
+
+// script returns the assertions of the path condition whose negate-th branch is negated.
+// Each assertion of a branch is annotated with the branch.
+func (s *smtLibSolver) script(negate int) string {
+	var b strings.Builder
+	s.writePrefix(&b, negate)
+	fmt.Fprintf(&b, "; negated %s\n(assert %s)\n", describeBranch(negate, s.branches[negate]), s.negation(negate))
+	return b.String()
+}
+
+// negation returns the negation of the condition of the i-th branch.
+func (s *smtLibSolver) negation(i int) string {
+	if neg, ok := s.negations[i]; ok {
+		return neg
+	}
+	return "(not " + s.conds[i] + ")"
+}
+
+// writePrefix writes the declarations, the axioms, and the assertions of the first n branches to b.
+func (s *smtLibSolver) writePrefix(b *strings.Builder, n int) {
+	for _, decl := range s.decls {
+		b.WriteString(decl + "\n")
+	}
+	for _, axiom := range s.axioms {
+		b.WriteString("(assert " + axiom + ")\n")
+	}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(b, "; %s\n(assert %s)\n", describeBranch(i, s.branches[i]), s.conds[i])
+	}
+}
+
+// Dump writes the query of Solve(negate) to w as a standalone SMT-LIB2 script.
+func (s *smtLibSolver) Dump(w io.Writer, negate int) error {
+	_, err := io.WriteString(w, smtLibPrelude+s.script(negate)+"(check-sat)\n(get-model)\n")
+	return err
+}
+
+// Solve solves the path condition whose negate-th branch is negated.
+func (s *smtLibSolver) Solve(negate int) ([]Solution, error) {
+	return s.solve(s.script(negate))
+}
+
+// solve checks the assertions of script and returns the values of the symbols.
+func (s *smtLibSolver) solve(script string) ([]Solution, error) {
+	// The values of the constants of the symbols are queried.
+	var leaves []ssa.Value
+	var terms []string
+	var collect func(v ssa.Value)
+	collect = func(v ssa.Value) {
+		if fields, ok := s.fields[v]; ok {
+			for _, f := range fields {
+				collect(f)
+			}
+			return
+		}
+		leaves = append(leaves, v)
+		terms = append(terms, s.terms[v])
+	}
+	for _, symbol := range s.symbols {
+		collect(symbol)
+	}
+
+	log.Debug.Printf("query:\n%s", script)
+	values, err := s.backend.check(script, terms, s.objectives(leaves))
+	if err != nil {
+		return nil, err
+	}
+	model := make(map[ssa.Value]sexp, len(leaves))
+	for i, v := range leaves {
+		model[v] = values[i]
+	}
+	solutions := make([]Solution, len(s.symbols))
+	for i, symbol := range s.symbols {
+		solutions[i], err = s.solution(model, symbol)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get a value for the symbol[%d]", i)
+		}
+	}
+	return solutions, nil
+}
+
+// objectives returns the objectives for readable values of leaves:
+// the absolute values of integers, the lengths of strings, and the printability of strings in this order.
+func (s *smtLibSolver) objectives(leaves []ssa.Value) []smtLibObjective {
+	var ints, lens, printables []smtLibObjective
+	for _, leaf := range leaves {
+		ty := leaf.Type().Underlying().(*types.Basic)
+		term := s.terms[leaf]
+		info := ty.Info()
+		switch {
+		case info&types.IsInteger > 0:
+			size := sizeOfBasicKind(ty.Kind())
+			if info&types.IsUnsigned == 0 {
+				term = fmt.Sprintf("(ite (bvslt %s (_ bv0 %d)) (bvneg %s) %s)", term, size, term, term)
+			}
+			ints = append(ints, smtLibObjective{term: term, width: size})
+		case info&types.IsString > 0:
+			lens = append(lens, smtLibObjective{term: "(str.len " + term + ")"})
+			printables = append(printables, smtLibObjective{
+				term: fmt.Sprintf(`(str.in_re %s (re.* (re.range " " "~")))`, term),
+				soft: true,
+			})
+		}
+	}
+	return append(append(ints, lens...), printables...)
+}
+
+func (s *smtLibSolver) solution(model map[ssa.Value]sexp, v ssa.Value) (Solution, error) {
+	if fields, ok := s.fields[v]; ok {
+		sols := make([]Solution, len(fields))
+		for i, f := range fields {
+			var err error
+			if sols[i], err = s.solution(model, f); err != nil {
+				return nil, err
+			}
+		}
+		return Definite{ty: v.Type(), value: sols}, nil
+	}
+	return smtLibBasicSolution(model[v], v.Type().Underlying().(*types.Basic))
+}
@@ -0,0 +1,99 @@
+package solver
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Overflows returns the integer operations and conversions in the trace that may overflow.
+func (s *smtLibSolver) Overflows() []ssa.Instruction {
+	return s.overflows
+}
+
+// SolveOverflow solves the path condition up to the i-th instruction of Overflows
+// with the condition that the instruction overflows.
+func (s *smtLibSolver) SolveOverflow(i int) ([]Solution, error) {
+	var b strings.Builder
+	s.writePrefix(&b, overflowPrefix(s.positions, s.overflowPositions[i]))
+	fmt.Fprintf(&b, "; overflow of %s\n(assert %s)\n", s.overflows[i], s.overflowConds[i])
+	return s.solve(b.String())
+}
+
+// addOverflow records the condition under which instr overflows if its result is symbolic.
+func (s *smtLibSolver) addOverflow(instr ssa.Instruction) {
+	if cond, ok := s.overflowCond(instr); ok {
+		s.overflows = append(s.overflows, instr)
+		s.overflowPositions = append(s.overflowPositions, s.pos)
+		s.overflowConds = append(s.overflowConds, cond)
+	}
+}
+
+// overflowCond returns the condition under which instr overflows.
+// Arithmetic operations are checked by comparing their results with those computed
+// in bit-vectors wide enough not to overflow.
+func (s *smtLibSolver) overflowCond(instr ssa.Instruction) (string, bool) {
+	v := instr.(ssa.Value)
+	r, ok := s.terms[v]
+	if !ok {
+		return "", false
+	}
+	ty, _ := overflowType(v.Type())
+	size := sizeOfBasicKind(ty.Kind())
+	unsigned := ty.Info()&types.IsUnsigned > 0
+
+	switch instr := instr.(type) {
+	case *ssa.BinOp:
+		x, _ := s.get(instr.X)
+		y, _ := s.get(instr.Y)
+		var op string
+		switch instr.Op {
+		case token.ADD:
+			op = "bvadd"
+		case token.SUB:
+			op = "bvsub"
+		case token.MUL:
+			op = "bvmul"
+		case token.SHL:
+			// The shift overflows if shifting the result back does not restore the operand.
+			op = "bvashr"
+			if unsigned {
+				op = "bvlshr"
+			}
+			return fmt.Sprintf("(not (= (%s %s %s) %s))", op, r, smtLibShiftCount(instr, y), x), true
+		}
+		wide := fmt.Sprintf("(%s %s %s)", op, smtLibExtend(x, size, unsigned), smtLibExtend(y, size, unsigned))
+		return fmt.Sprintf("(not (= %s %s))", wide, smtLibExtend(r, size, unsigned)), true
+	case *ssa.UnOp:
+		// Only the negation of the minimum value overflows.
+		x, _ := s.get(instr.X)
+		return fmt.Sprintf("(= %s (_ bv%d %d))", x, uint64(1)<<(size-1), size), true
+	case *ssa.Convert:
+		// The conversion overflows if the operand and the result differ as integers of a sufficient size.
+		from := instr.X.Type().Underlying().(*types.Basic)
+		fromSize := sizeOfBasicKind(from.Kind())
+		wide := size
+		if fromSize > wide {
+			wide = fromSize
+		}
+		wide++
+		x, _ := s.get(instr.X)
+		return fmt.Sprintf("(not (= %s %s))",
+			smtLibExtend(x, wide-fromSize, from.Info()&types.IsUnsigned > 0), smtLibExtend(r, wide-size, unsigned)), true
+	}
+	return "", false
+}
+
+// smtLibExtend extends the bit-vector term by n bits.
+func smtLibExtend(term string, n uint, unsigned bool) string {
+	if n == 0 {
+		return term
+	}
+	if unsigned {
+		return fmt.Sprintf("((_ zero_extend %d) %s)", n, term)
+	}
+	return fmt.Sprintf("((_ sign_extend %d) %s)", n, term)
+}
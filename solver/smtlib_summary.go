@@ -0,0 +1,69 @@
+package solver
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// loadLoopExit adds the BranchLoop of the counting loop l, which has exited after the first iteration.
+// The value of the induction variable at the exit is a fresh constant summarized as Z3Solver.loadLoopExit does.
+func (s *smtLibSolver) loadLoopExit(l *CountingLoop) {
+	if _, ok := l.Bound.(*ssa.Const); ok {
+		// The trip count is fixed.
+		return
+	}
+	bound, ok := s.get(l.Bound)
+	if !ok {
+		return
+	}
+	current, ok := s.get(l.Induction)
+	if !ok {
+		return
+	}
+	ty := l.Induction.Type().Underlying().(*types.Basic)
+	start, _ := smtLibConst(l.Start)
+	step, _ := smtLibConst(l.Step)
+	x := fmt.Sprintf("|exit(%s)-%d|", l.Induction.Name(), len(s.decls))
+	s.decls = append(s.decls, fmt.Sprintf("(declare-const %s %s)", x, smtLibSort(ty)))
+
+	unsigned := ty.Info()&types.IsUnsigned > 0
+	// compare returns the comparison by op of the integers of ty.
+	compare := func(op token.Token, x, y string) string {
+		var f string
+		switch op {
+		case token.LSS:
+			f = "bvslt"
+		case token.LEQ:
+			f = "bvsle"
+		case token.GTR:
+			f = "bvsgt"
+		case token.GEQ:
+			f = "bvsge"
+		default:
+			return fmt.Sprintf("(distinct %s %s)", x, y)
+		}
+		if unsigned {
+			f = "bvu" + f[3:]
+		}
+		return fmt.Sprintf("(%s %s %s)", f, x, y)
+	}
+	var beyond, distance, prev string
+	if l.Decrement {
+		beyond = compare(token.LSS, x, start)
+		distance = fmt.Sprintf("(bvsub %s %s)", start, x)
+		prev = fmt.Sprintf("(bvadd %s %s)", x, step)
+	} else {
+		beyond = compare(token.GTR, x, start)
+		distance = fmt.Sprintf("(bvsub %s %s)", x, start)
+		prev = fmt.Sprintf("(bvsub %s %s)", x, step)
+	}
+	summary := fmt.Sprintf("(and %s (= (bvurem %s %s) (_ bv0 %d)) (not %s) %s)",
+		beyond, distance, step, sizeOfBasicKind(ty.Kind()), compare(l.Op, x, bound), compare(l.Op, prev, bound))
+
+	s.negations[len(s.branches)] = fmt.Sprintf("(and %s (distinct %s %s))", summary, x, current)
+	s.addBranch(&BranchLoop{instr: l.If}, summary)
+	s.terms[l.Induction] = x
+}
@@ -0,0 +1,483 @@
+package solver
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"math"
+	"strconv"
+
+	"github.com/ajalab/congo/log"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ssa"
+)
+
+// smtLibSort returns the SMT-LIB2 sort of values of ty.
+func smtLibSort(ty *types.Basic) string {
+	info := ty.Info()
+	switch {
+	case info&types.IsBoolean > 0:
+		return "Bool"
+	case info&types.IsInteger > 0:
+		return fmt.Sprintf("(_ BitVec %d)", sizeOfBasicKind(ty.Kind()))
+	case info&types.IsFloat > 0:
+		if ty.Kind() == types.Float32 {
+			return "(_ FloatingPoint 8 24)"
+		}
+		return "(_ FloatingPoint 11 53)"
+	case info&types.IsString > 0:
+		return "String"
+	}
+	return ""
+}
+
+// smtLibFloatSort returns the exponent and the significand widths of a floating-point type ty.
+func smtLibFloatSort(ty *types.Basic) (int, int) {
+	if ty.Kind() == types.Float32 {
+		return 8, 24
+	}
+	return 11, 53
+}
+
+func (s *smtLibSolver) loadSymbol(symbol ssa.Value, name string) error {
+	if !smtLibSupports(symbol.Type()) {
+		return errors.Errorf("symbols of type %v are not supported by the smtlib backend", symbol.Type())
+	}
+	switch ty := symbol.Type().Underlying().(type) {
+	case *types.Basic:
+		term := "|" + name + "|"
+		s.decls = append(s.decls, fmt.Sprintf("(declare-const %s %s)", term, smtLibSort(ty)))
+		s.terms[symbol] = term
+		if ty.Info()&types.IsString > 0 {
+			// Each character of a string is a byte.
+			s.axioms = append(s.axioms, fmt.Sprintf(`(str.in_re %s (re.* (re.range "\u{0}" "\u{ff}")))`, term))
+		}
+		return nil
+	case *types.Struct:
+		// Each field is a symbolic variable on its own.
+		n := ty.NumFields()
+		fields := make([]ssa.Value, n)
+		for i := 0; i < n; i++ {
+			fields[i] = &field{Value: symbol, index: i}
+			if err := s.loadSymbol(fields[i], name+"."+ty.Field(i).Name()); err != nil {
+				return err
+			}
+		}
+		s.fields[symbol] = fields
+	}
+	return nil
+}
+
+// loadSymbols loads symbolic variables to the solver.
+func (s *smtLibSolver) loadSymbols(symbols []ssa.Value) error {
+	for i, symbol := range symbols {
+		name := fmt.Sprintf("%s%d", z3SymbolPrefixForSymbol, i)
+		if err := s.loadSymbol(symbol, name); err != nil {
+			return err
+		}
+	}
+	s.symbols = symbols
+	return nil
+}
+
+// loadTrace loads the path condition of the trace instrs.
+// Values that the backend does not support (e.g., pointers and slices) are regarded as concrete.
+func (s *smtLibSolver) loadTrace(instrs []ssa.Instruction, isComplete bool) error {
+	return walkTrace(s, instrs, isComplete, s.backend.overflow, s.backend.loops)
+}
+
+func (s *smtLibSolver) begin(i int, instr ssa.Instruction) {
+	s.pos = i
+	if v, ok := instr.(ssa.Value); ok {
+		// The value computed in the previous iteration of a loop is discarded.
+		delete(s.terms, v)
+	}
+}
+
+func (s *smtLibSolver) bindTuple(v ssa.Value, elems []ssa.Value) {
+	s.tuples[v] = elems
+}
+
+func (s *smtLibSolver) loadInstr(instr, next ssa.Instruction) (callAction, error) {
+	switch instr := instr.(type) {
+	case *ssa.UnOp:
+		if instr.Op == token.MUL {
+			// Only the values stored in local allocations are tracked.
+			if ref, ok := s.refs[instr.X]; ok {
+				s.bind(instr, ref)
+			}
+			break
+		}
+		if err := s.unop(instr); err != nil {
+			log.Error.Print(err)
+		}
+	case *ssa.BinOp:
+		if cond, ok := s.divisorCond(instr); ok {
+			s.addBranch(&BranchDivZero{
+				instr:   instr,
+				success: true,
+			}, cond)
+		}
+		if err := s.binop(instr); err != nil {
+			log.Error.Print(err)
+		}
+	case *ssa.Call:
+		if fn, ok := instr.Call.Value.(*ssa.Builtin); ok && fn.Name() == "len" {
+			arg := instr.Call.Args[0]
+			if basicTy, ok := arg.Type().Underlying().(*types.Basic); ok && basicTy.Info()&types.IsString > 0 {
+				if x, ok := s.get(arg); ok {
+					s.define(instr, fmt.Sprintf("((_ int2bv %d) (str.len %s))", strconv.IntSize, x))
+				}
+			}
+		}
+	case *ssa.Store:
+		s.store(instr.Addr, instr.Val)
+	case *ssa.FieldAddr:
+		if ref, ok := s.refs[instr.X]; ok {
+			if fields, ok := s.fields[ref]; ok {
+				s.refs[instr] = fields[instr.Field]
+			}
+		}
+	case *ssa.Field:
+		if fields, ok := s.fields[instr.X]; ok {
+			s.bind(instr, fields[instr.Field])
+		}
+	case *ssa.Extract:
+		if elems, ok := s.tuples[instr.Tuple]; ok {
+			s.bind(instr, elems[instr.Index])
+		}
+	case *ssa.ChangeType:
+		s.bind(instr, instr.X)
+	case *ssa.Convert:
+		s.convert(instr)
+	}
+	return callDefault, nil
+}
+
+func (s *smtLibSolver) loadIf(instr *ssa.If, direction bool) {
+	cond, ok := s.get(instr.Cond)
+	if !ok {
+		return
+	}
+	if !direction {
+		cond = "(not " + cond + ")"
+	}
+	s.addBranch(&BranchIf{
+		instr:     instr,
+		direction: direction,
+	}, cond)
+}
+
+// loadPanic adds the branch of the instruction that caused a panic.
+// Only the panics of integer division are modelled by this backend.
+func (s *smtLibSolver) loadPanic(causeInstr ssa.Instruction) error {
+	if instr, ok := causeInstr.(*ssa.BinOp); ok {
+		if cond, ok := s.divisorCond(instr); ok {
+			s.addBranch(&BranchDivZero{
+				instr:   instr,
+				success: false,
+			}, "(not "+cond+")")
+		}
+	}
+	return nil
+}
+
+// divisorCond returns the condition that the divisor of an integer division or remainder is not zero.
+// It reports false if instr is not such an operation or its divisor is not symbolic.
+func (s *smtLibSolver) divisorCond(instr *ssa.BinOp) (string, bool) {
+	if instr.Op != token.QUO && instr.Op != token.REM {
+		return "", false
+	}
+	ty, ok := instr.Y.Type().Underlying().(*types.Basic)
+	if !ok || ty.Info()&types.IsInteger == 0 {
+		return "", false
+	}
+	y, ok := s.terms[instr.Y]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("(not (= %s (_ bv0 %d)))", y, sizeOfBasicKind(ty.Kind())), true
+}
+
+func (s *smtLibSolver) addBranch(b Branch, cond string) {
+	s.branches = append(s.branches, b)
+	s.positions = append(s.positions, s.pos)
+	s.conds = append(s.conds, cond)
+}
+
+// store updates the value referenced by addr with val.
+// If addr is the address of a struct field, the struct that contains it is replaced with an updated copy.
+func (s *smtLibSolver) store(addr, val ssa.Value) {
+	s.refs[addr] = val
+	fieldAddr, ok := addr.(*ssa.FieldAddr)
+	if !ok {
+		return
+	}
+	ref, ok := s.refs[fieldAddr.X]
+	if !ok {
+		return
+	}
+	fields, ok := s.fields[ref]
+	if !ok {
+		return
+	}
+	c := &clone{ref}
+	s.fields[c] = make([]ssa.Value, len(fields))
+	copy(s.fields[c], fields)
+	s.fields[c][fieldAddr.Field] = val
+	s.store(fieldAddr.X, c)
+}
+
+// define names the term expr as the value of v.
+// Terms are named so that their sizes do not grow exponentially when they are shared.
+func (s *smtLibSolver) define(v ssa.Value, expr string) {
+	ty, ok := v.Type().Underlying().(*types.Basic)
+	if !ok {
+		return
+	}
+	name := fmt.Sprintf("|t-%d|", len(s.decls))
+	s.decls = append(s.decls, fmt.Sprintf("(define-fun %s () %s %s)", name, smtLibSort(ty), expr))
+	s.terms[v] = name
+}
+
+// bind makes dst have the same symbolic representation as src.
+func (s *smtLibSolver) bind(dst, src ssa.Value) {
+	if fields, ok := s.fields[src]; ok {
+		s.fields[dst] = fields
+		return
+	}
+	if elems, ok := s.tuples[src]; ok {
+		s.tuples[dst] = elems
+		return
+	}
+	if term, ok := s.get(src); ok {
+		s.terms[dst] = term
+	} else {
+		delete(s.terms, dst)
+	}
+}
+
+func (s *smtLibSolver) get(v ssa.Value) (string, bool) {
+	if c, ok := v.(*ssa.Const); ok {
+		return smtLibConst(c)
+	}
+	term, ok := s.terms[v]
+	return term, ok
+}
+
+// smtLibConst returns the SMT-LIB2 term of a constant of a supported basic type.
+func smtLibConst(c *ssa.Const) (string, bool) {
+	ty, ok := c.Type().Underlying().(*types.Basic)
+	if !ok || c.Value == nil {
+		return "", false
+	}
+	info := ty.Info()
+	switch {
+	case info&types.IsBoolean > 0:
+		return strconv.FormatBool(constant.BoolVal(c.Value)), true
+	case info&types.IsInteger > 0:
+		size := sizeOfBasicKind(ty.Kind())
+		u := c.Uint64()
+		if info&types.IsUnsigned == 0 {
+			u = uint64(c.Int64())
+		}
+		if size < 64 {
+			u &= 1<<size - 1
+		}
+		return fmt.Sprintf("(_ bv%d %d)", u, size), true
+	case info&types.IsFloat > 0:
+		f, _ := constant.Float64Val(c.Value)
+		if ty.Kind() == types.Float32 {
+			return fmt.Sprintf("((_ to_fp 8 24) #x%08x)", math.Float32bits(float32(f))), true
+		}
+		return fmt.Sprintf("((_ to_fp 11 53) #x%016x)", math.Float64bits(f)), true
+	case info&types.IsString > 0:
+		return smtLibString(constant.StringVal(c.Value)), true
+	}
+	return "", false
+}
+
+func (s *smtLibSolver) unop(instr *ssa.UnOp) error {
+	x, ok := s.get(instr.X)
+	if !ok {
+		return nil
+	}
+	ty, ok := instr.X.Type().Underlying().(*types.Basic)
+	if !ok {
+		return errors.Errorf("unop: not implemented: %v", instr)
+	}
+	switch instr.Op {
+	case token.SUB:
+		if ty.Info()&types.IsFloat > 0 {
+			s.define(instr, "(fp.neg "+x+")")
+		} else {
+			s.define(instr, "(bvneg "+x+")")
+		}
+	case token.NOT:
+		s.define(instr, "(not "+x+")")
+	case token.XOR:
+		s.define(instr, "(bvnot "+x+")")
+	default:
+		return errors.Errorf("unop: not implemented: %v", instr)
+	}
+	return nil
+}
+
+func (s *smtLibSolver) binop(instr *ssa.BinOp) error {
+	x, xok := s.get(instr.X)
+	y, yok := s.get(instr.Y)
+	if !xok || !yok {
+		// The result is concrete if an operand is concrete or not supported.
+		return nil
+	}
+	ty, ok := instr.X.Type().Underlying().(*types.Basic)
+	if !ok {
+		return errors.Errorf("binop: not implemented: %v", instr)
+	}
+	info := ty.Info()
+	isFloat, isString, isUnsigned := info&types.IsFloat > 0, info&types.IsString > 0, info&types.IsUnsigned > 0
+
+	// choose returns the operator for the type of the operands.
+	choose := func(signed, unsigned, float, str string) string {
+		switch {
+		case isFloat:
+			return float
+		case isString:
+			return str
+		case isUnsigned:
+			return unsigned
+		}
+		return signed
+	}
+	var op string
+	switch instr.Op {
+	case token.ADD:
+		op = choose("bvadd", "bvadd", "fp.add RNE", "str.++")
+	case token.SUB:
+		op = choose("bvsub", "bvsub", "fp.sub RNE", "")
+	case token.MUL:
+		op = choose("bvmul", "bvmul", "fp.mul RNE", "")
+	case token.QUO:
+		op = choose("bvsdiv", "bvudiv", "fp.div RNE", "")
+	case token.REM:
+		op = choose("bvsrem", "bvurem", "", "")
+	case token.EQL:
+		op = choose("=", "=", "fp.eq", "=")
+	case token.NEQ:
+		op = choose("distinct", "distinct", "", "distinct")
+		if isFloat {
+			s.define(instr, fmt.Sprintf("(not (fp.eq %s %s))", x, y))
+			return nil
+		}
+	case token.LSS:
+		op = choose("bvslt", "bvult", "fp.lt", "str.<")
+	case token.LEQ:
+		op = choose("bvsle", "bvule", "fp.leq", "str.<=")
+	case token.GTR:
+		op = choose("bvsgt", "bvugt", "fp.gt", "str.<")
+		if isString {
+			x, y = y, x
+		}
+	case token.GEQ:
+		op = choose("bvsge", "bvuge", "fp.geq", "str.<=")
+		if isString {
+			x, y = y, x
+		}
+	case token.AND:
+		op = "bvand"
+	case token.OR:
+		op = "bvor"
+	case token.XOR:
+		op = "bvxor"
+	case token.AND_NOT:
+		op, y = "bvand", "(bvnot "+y+")"
+	case token.SHL, token.SHR:
+		y = smtLibShiftCount(instr, y)
+		if instr.Op == token.SHL {
+			op = "bvshl"
+		} else {
+			// Arithmetic shifts for signed integers and logical shifts for unsigned integers.
+			op = choose("bvashr", "bvlshr", "", "")
+		}
+	case token.LAND:
+		op = "and"
+	case token.LOR:
+		op = "or"
+	}
+	if op == "" {
+		return errors.Errorf("binop: not implemented: %v", instr)
+	}
+	s.define(instr, fmt.Sprintf("(%s %s %s)", op, x, y))
+	return nil
+}
+
+// smtLibShiftCount resizes the shift count y of instr to the width of the left operand.
+func smtLibShiftCount(instr *ssa.BinOp, y string) string {
+	xsize := sizeOfBasicKind(instr.X.Type().Underlying().(*types.Basic).Kind())
+	ysize := xsize
+	if yty, ok := instr.Y.Type().Underlying().(*types.Basic); ok {
+		ysize = sizeOfBasicKind(yty.Kind())
+	}
+	if xsize > ysize {
+		return fmt.Sprintf("((_ zero_extend %d) %s)", xsize-ysize, y)
+	} else if xsize < ysize {
+		return fmt.Sprintf("((_ extract %d 0) %s)", xsize-1, y)
+	}
+	return y
+}
+
+// convert loads a conversion between basic types.
+func (s *smtLibSolver) convert(instr *ssa.Convert) {
+	from, ok := instr.X.Type().Underlying().(*types.Basic)
+	if !ok {
+		return
+	}
+	to, ok := instr.Type().Underlying().(*types.Basic)
+	if !ok {
+		return
+	}
+	x, ok := s.get(instr.X)
+	if !ok {
+		return
+	}
+	fromInfo, toInfo := from.Info(), to.Info()
+	switch {
+	case fromInfo&types.IsInteger > 0 && toInfo&types.IsInteger > 0:
+		fromSize, toSize := sizeOfBasicKind(from.Kind()), sizeOfBasicKind(to.Kind())
+		switch {
+		case fromSize > toSize:
+			s.define(instr, fmt.Sprintf("((_ extract %d 0) %s)", toSize-1, x))
+		case fromSize < toSize && fromInfo&types.IsUnsigned > 0:
+			s.define(instr, fmt.Sprintf("((_ zero_extend %d) %s)", toSize-fromSize, x))
+		case fromSize < toSize:
+			s.define(instr, fmt.Sprintf("((_ sign_extend %d) %s)", toSize-fromSize, x))
+		default:
+			s.terms[instr] = x
+		}
+	case fromInfo&types.IsInteger > 0 && toInfo&types.IsFloat > 0:
+		eb, sb := smtLibFloatSort(to)
+		op := "to_fp"
+		if fromInfo&types.IsUnsigned > 0 {
+			op = "to_fp_unsigned"
+		}
+		s.define(instr, fmt.Sprintf("((_ %s %d %d) RNE %s)", op, eb, sb, x))
+	case fromInfo&types.IsFloat > 0 && toInfo&types.IsInteger > 0:
+		// The fractional part is discarded (truncation towards zero).
+		op := "fp.to_sbv"
+		if toInfo&types.IsUnsigned > 0 {
+			op = "fp.to_ubv"
+		}
+		s.define(instr, fmt.Sprintf("((_ %s %d) RTZ %s)", op, sizeOfBasicKind(to.Kind()), x))
+	case fromInfo&types.IsFloat > 0 && toInfo&types.IsFloat > 0:
+		if from.Kind() == to.Kind() {
+			s.terms[instr] = x
+			break
+		}
+		eb, sb := smtLibFloatSort(to)
+		s.define(instr, fmt.Sprintf("((_ to_fp %d %d) RNE %s)", eb, sb, x))
+	case fromInfo&types.IsString > 0 && toInfo&types.IsString > 0:
+		s.terms[instr] = x
+	}
+}
@@ -1,6 +1,10 @@
 package solver
 
-import "go/types"
+import (
+	"go/types"
+
+	"github.com/ajalab/congo/interp"
+)
 
 // Solution represents a solution for a symbol.
 type Solution interface {
@@ -11,6 +15,11 @@ type Solution interface {
 // Definite represents a solution.
 // If ty is *types.Pointer, then the value is an instance of Solution
 // which represents the referenced value.
+// If ty is a struct, slice, or array type, then the value is a slice of Solution
+// which represents the values of fields or elements.
+// If ty is a map type, then the value is a slice of mapEntry.
+// If ty is an interface type, then the value is either nil or an instance of Solution
+// which represents the dynamic value.
 type Definite struct {
 	ty    types.Type
 	value interface{}
@@ -35,9 +44,49 @@ func (s Definite) Concretize(f func(types.Type) interface{}) interface{} {
 		//TODO(ajalab): remove panic
 		panic("unreachable")
 	}
+	if _, ok := s.ty.Underlying().(*types.Interface); ok {
+		sub, ok := s.value.(Solution)
+		if !ok {
+			return nil
+		}
+		return interp.SymbolicValue{
+			Value: sub.Concretize(f),
+			Type:  sub.Type(),
+		}
+	}
+	switch subs := s.value.(type) {
+	case []Solution:
+		values := make([]interface{}, len(subs))
+		for i, sub := range subs {
+			values[i] = sub.Concretize(f)
+		}
+		return values
+	case []mapEntry:
+		values := make(map[interface{}]interface{}, len(subs))
+		for _, entry := range subs {
+			values[entry.key.Concretize(f)] = entry.value.Concretize(f)
+		}
+		return values
+	}
 	return s.value
 }
 
+// NewReference returns a new solution of the pointer type ty that refers to elem.
+func NewReference(ty *types.Pointer, elem Solution) Definite {
+	return Definite{ty: ty, value: elem}
+}
+
+// NewDynamic returns a new solution of the interface type ty whose dynamic value is sub.
+func NewDynamic(ty types.Type, sub Solution) Definite {
+	return Definite{ty: ty, value: sub}
+}
+
+// mapEntry represents a solution for an entry of a map.
+type mapEntry struct {
+	key   Solution
+	value Solution
+}
+
 // Indefinite represents an indefinite solution.
 type Indefinite struct {
 	ty types.Type
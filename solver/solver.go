@@ -1,3 +1,6 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
 package solver
 
 import (
@@ -14,7 +17,9 @@ import (
 	"go/constant"
 	"go/token"
 	"go/types"
+	"math"
 	"strconv"
+	"time"
 	"unsafe"
 
 	"github.com/ajalab/congo/log"
@@ -23,63 +28,198 @@ import (
 	"golang.org/x/tools/go/ssa"
 )
 
-const (
-	z3SymbolPrefixForSymbol string = "symbol-"
-)
-
 func z3MkStringSymbol(ctx C.Z3_context, s string) C.Z3_symbol {
 	c := C.CString(s)
 	defer C.free(unsafe.Pointer(c))
 	return C.Z3_mk_string_symbol(ctx, c)
 }
 
-// Z3Solver is a type that holds the Z3 context, assertions, and symbols.
-type Z3Solver struct {
-	asts     map[ssa.Value]C.Z3_ast
-	refs     map[ssa.Value]ssa.Value
-	nonnull  map[ssa.Value]struct{}
-	ctx      C.Z3_context
-	branches []Branch
-	symbols  []ssa.Value
+// z3MkFreshConst returns a new constant of sort whose name starts with prefix.
+func z3MkFreshConst(ctx C.Z3_context, prefix string, sort C.Z3_sort) C.Z3_ast {
+	c := C.CString(prefix)
+	defer C.free(unsafe.Pointer(c))
+	return C.Z3_mk_fresh_const(ctx, c, sort)
 }
 
-//export goZ3ErrorHandler
-func goZ3ErrorHandler(ctx C.Z3_context, e C.Z3_error_code) {
-	msg := C.Z3_get_error_msg(ctx, e)
-	panic("Z3 error occurred: " + C.GoString(msg))
+// Z3Context is a Z3 context shared by the solvers of a target.
+// Since the context is shared, ASTs that are structurally identical (e.g., symbols) are shared
+// among the solvers. The context is deleted when it and all the solvers using it are closed.
+// refs counts the users of the context; the ASTs themselves are not reference-counted.
+type Z3Context struct {
+	ctx  C.Z3_context
+	refs int
+	// stringFuncs caches the declarations of string functions (see stringFunc).
+	stringFuncs map[string]C.Z3_func_decl
+	// cache maps the keys of queries (see queryKey) to their results.
+	cache map[string]queryResult
+	// Timeout is the time limit of each query. No limit is imposed if it is zero.
+	Timeout time.Duration
+	// Readable makes the solvers prefer readable models (see readableModel).
+	Readable bool
+	// Overflow makes the solvers record the conditions of overflows (see Z3Solver.Overflows).
+	Overflow bool
+	// Loops returns the counting loops to be summarized (see CountingLoop).
+	Loops Loops
 }
 
-// CreateZ3Solver returns a new Z3Solver.
-func CreateZ3Solver(symbols []ssa.Value, instrs []ssa.Instruction, isComplete bool) (*Z3Solver, error) {
+// NewZ3Context returns a new Z3Context.
+func NewZ3Context() *Z3Context {
 	cfg := C.Z3_mk_config()
 	defer C.Z3_del_config(cfg)
 
 	// TODO(ajalab): We may have to use Z3_mk_context_rc and manually handle the reference count.
+	// The ASTs are never released before the context is deleted, so the memory used by a context
+	// shared by a target grows with the number of runs until the execution ends.
 	ctx := C.Z3_mk_context(cfg)
 	C.Z3_set_error_handler(ctx, (*C.Z3_error_handler)(C.goZ3ErrorHandler))
+	return &Z3Context{
+		ctx:         ctx,
+		refs:        1,
+		stringFuncs: make(map[string]C.Z3_func_decl),
+		cache:       make(map[string]queryResult),
+	}
+}
 
+func init() {
+	registerBackend("z3", func(string) (Backend, error) {
+		return NewZ3Context(), nil
+	}, func(types.Type) bool { return true }, true)
+}
+
+// NewSolver returns a new Z3Solver on the context.
+func (c *Z3Context) NewSolver(symbols []ssa.Value, concreteTypes []types.Type, instrs []ssa.Instruction, isComplete bool) (Solver, error) {
+	s, err := CreateZ3Solver(c, symbols, concreteTypes, instrs, isComplete)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetTimeout sets Timeout.
+func (c *Z3Context) SetTimeout(timeout time.Duration) {
+	c.Timeout = timeout
+}
+
+// SetReadable sets Readable.
+func (c *Z3Context) SetReadable(readable bool) {
+	c.Readable = readable
+}
+
+// SetOverflow sets Overflow.
+func (c *Z3Context) SetOverflow(overflow bool) {
+	c.Overflow = overflow
+}
+
+// SetLoops sets Loops.
+func (c *Z3Context) SetLoops(loops Loops) {
+	c.Loops = loops
+}
+
+func (c *Z3Context) acquire() {
+	c.refs++
+}
+
+// Interrupt interrupts the query being solved, which results in UnknownError.
+// It is safe to call Interrupt from another goroutine while the context is not closed.
+func (c *Z3Context) Interrupt() {
+	C.Z3_interrupt(c.ctx)
+}
+
+// Close releases the context. The Z3 context is deleted if no solver is using it.
+func (c *Z3Context) Close() {
+	c.refs--
+	if c.refs == 0 {
+		C.Z3_del_context(c.ctx)
+	}
+}
+
+// Z3Solver is a type that holds the Z3 context, assertions, and symbols.
+// It keeps a Z3 solver to check the path conditions incrementally.
+type Z3Solver struct {
+	asts          map[ssa.Value]C.Z3_ast
+	refs          map[ssa.Value]ssa.Value
+	fields        map[ssa.Value][]ssa.Value
+	slices        map[ssa.Value]sliceAST
+	maps          map[ssa.Value]*mapAST
+	ifaces        map[ssa.Value]*ifaceAST
+	iters         map[ssa.Value]*stringIter
+	tuples        map[ssa.Value][]ssa.Value
+	nonnull       map[ssa.Value]struct{}
+	context       *Z3Context
+	ctx           C.Z3_context
+	solver        C.Z3_solver
+	concreteTypes []types.Type
+	branches      []Branch
+	positions     []int
+	conds         []C.Z3_ast
+	axioms        []C.Z3_ast
+	symbols       []ssa.Value
+	// leaves are the constants of basic types that constitute the symbols.
+	leaves []leaf
+	// overflows are the instructions that may overflow, which are loaded
+	// at overflowPositions of the trace and overflow under overflowConds.
+	overflows         []ssa.Instruction
+	overflowPositions []int
+	overflowConds     []C.Z3_ast
+	// negations maps the indices of the branches whose negations are not
+	// the negations of their conditions (see BranchLoop) to the negations.
+	negations map[int]C.Z3_ast
+
+	// asserted is the list of the constraints asserted to solver.
+	// Each constraint is asserted in its own scope so that the common prefix can be shared among queries.
+	asserted []C.Z3_ast
+
+	// pos is the index of the instruction being loaded in the trace.
+	pos int
+}
+
+//export goZ3ErrorHandler
+func goZ3ErrorHandler(ctx C.Z3_context, e C.Z3_error_code) {
+	msg := C.Z3_get_error_msg(ctx, e)
+	panic("Z3 error occurred: " + C.GoString(msg))
+}
+
+// CreateZ3Solver returns a new Z3Solver on the context.
+// concreteTypes are the candidates for dynamic types of symbolic interface values.
+func CreateZ3Solver(context *Z3Context, symbols []ssa.Value, concreteTypes []types.Type, instrs []ssa.Instruction, isComplete bool) (*Z3Solver, error) {
+	context.acquire()
 	s := &Z3Solver{
-		asts:    make(map[ssa.Value]C.Z3_ast),
-		refs:    make(map[ssa.Value]ssa.Value),
-		nonnull: make(map[ssa.Value]struct{}),
-		ctx:     ctx,
+		asts:          make(map[ssa.Value]C.Z3_ast),
+		refs:          make(map[ssa.Value]ssa.Value),
+		fields:        make(map[ssa.Value][]ssa.Value),
+		slices:        make(map[ssa.Value]sliceAST),
+		maps:          make(map[ssa.Value]*mapAST),
+		ifaces:        make(map[ssa.Value]*ifaceAST),
+		iters:         make(map[ssa.Value]*stringIter),
+		tuples:        make(map[ssa.Value][]ssa.Value),
+		nonnull:       make(map[ssa.Value]struct{}),
+		negations:     make(map[int]C.Z3_ast),
+		context:       context,
+		ctx:           context.ctx,
+		concreteTypes: concreteTypes,
 	}
 
 	err := s.loadSymbols(symbols)
 	if err != nil {
+		s.Close()
 		return nil, errors.Wrap(err, "failed to load symbols")
 	}
 	err = s.loadTrace(instrs, isComplete)
 	if err != nil {
+		s.Close()
 		return nil, errors.Wrap(err, "failed to load trace")
 	}
 
 	return s, nil
 }
 
-// Close deletes the Z3 context.
+// Close releases the Z3 solver and the context.
 func (s *Z3Solver) Close() {
-	C.Z3_del_context(s.ctx)
+	if s.solver != nil {
+		C.Z3_solver_dec_ref(s.ctx, s.solver)
+		s.solver = nil
+	}
+	s.context.Close()
 }
 
 func newBasicSort(ctx C.Z3_context, ty *types.Basic) C.Z3_sort {
@@ -89,6 +229,11 @@ func newBasicSort(ctx C.Z3_context, ty *types.Basic) C.Z3_sort {
 		return C.Z3_mk_bool_sort(ctx)
 	case info&types.IsInteger > 0:
 		return C.Z3_mk_bv_sort(ctx, C.uint(sizeOfBasicKind(ty.Kind())))
+	case info&types.IsFloat > 0:
+		if ty.Kind() == types.Float32 {
+			return C.Z3_mk_fpa_sort_single(ctx)
+		}
+		return C.Z3_mk_fpa_sort_double(ctx)
 	case info&types.IsString > 0:
 		return C.Z3_mk_string_sort(ctx)
 	}
@@ -97,14 +242,23 @@ func newBasicSort(ctx C.Z3_context, ty *types.Basic) C.Z3_sort {
 	panic("unimplemented")
 }
 
+// z3MakeRoundingMode returns the rounding mode of floating-point arithmetic in Go (round to nearest even).
+func z3MakeRoundingMode(ctx C.Z3_context) C.Z3_ast {
+	return C.Z3_mk_fpa_round_nearest_ties_to_even(ctx)
+}
+
 func (s *Z3Solver) loadSymbol(symbol ssa.Value, name string) {
-	ty := symbol.Type()
+	ty := symbol.Type().Underlying()
 	z3Symbol := z3MkStringSymbol(s.ctx, name)
 	switch ty := ty.(type) {
 	case *types.Basic:
 		sort := newBasicSort(s.ctx, ty)
 		ast := C.Z3_mk_const(s.ctx, z3Symbol, sort)
 		s.asts[symbol] = ast
+		s.leaves = append(s.leaves, leaf{ast: ast, ty: ty})
+		if ty.Info()&types.IsString > 0 {
+			s.axioms = append(s.axioms, z3MakeByteString(s.ctx, ast))
+		}
 	case *types.Pointer:
 		// ast represents a symbolic address
 		sort := C.Z3_mk_int_sort(s.ctx)
@@ -114,6 +268,21 @@ func (s *Z3Solver) loadSymbol(symbol ssa.Value, name string) {
 		ref := &ref{symbol}
 		s.refs[symbol] = ref
 		s.loadSymbol(ref, "*"+name)
+	case *types.Struct:
+		// Each field is a symbolic variable on its own.
+		n := ty.NumFields()
+		fields := make([]ssa.Value, n)
+		for i := 0; i < n; i++ {
+			fields[i] = &field{Value: symbol, index: i}
+			s.loadSymbol(fields[i], name+"."+ty.Field(i).Name())
+		}
+		s.fields[symbol] = fields
+	case *types.Slice, *types.Array:
+		s.loadSliceSymbol(symbol, name)
+	case *types.Map:
+		s.loadMapSymbol(symbol, name)
+	case *types.Interface:
+		s.loadIfaceSymbol(symbol, name)
 	}
 }
 
@@ -123,7 +292,15 @@ func (s *Z3Solver) loadSymbols(symbols []ssa.Value) error {
 	for i, symbol := range symbols {
 		// TODO(ajalab): rename
 		name := fmt.Sprintf("%s%d", z3SymbolPrefixForSymbol, i)
+		n := len(s.leaves)
 		s.loadSymbol(symbol, name)
+		for j := n; j < len(s.leaves); j++ {
+			s.leaves[j].symbol = i
+		}
+		// Symbols of interface types must not be nil since the runner asserts their types.
+		if _, ok := s.ifaces[symbol]; ok {
+			s.axioms = append(s.axioms, C.Z3_mk_not(s.ctx, C.Z3_mk_eq(s.ctx, s.asts[symbol], z3MakeTypeID(s.ctx, 0))))
+		}
 	}
 	copy(s.symbols, symbols)
 	return nil
@@ -131,128 +308,169 @@ func (s *Z3Solver) loadSymbols(symbols []ssa.Value) error {
 
 // loadTrace loads a running trace to the solver.
 func (s *Z3Solver) loadTrace(instrs []ssa.Instruction, isComplete bool) error {
-	var currentBlock *ssa.BasicBlock
-	var prevBlock *ssa.BasicBlock
-	var callStack []*ssa.Call
-
-	// If the trace is not complete, ignore the last instruction,
-	// which is a cause of failure.
-	n := len(instrs)
-	if !isComplete {
-		n = n - 1
-	}
-
-	for i := 0; i < n; i++ {
-		instr := instrs[i]
-		block := instr.Block()
-		if currentBlock != block {
-			prevBlock = currentBlock
-			currentBlock = block
-			log.Debug.Printf("block: %v.%s", block.Parent(), block)
-		}
-
-		switch instr := instr.(type) {
-		case *ssa.UnOp:
-			var err error
-			if instr.Op == token.MUL {
-				s.asts[instr], err = s.deref(instr)
-			} else {
-				s.asts[instr], err = s.unop(instr)
-			}
-			if err != nil {
-				log.Error.Print(err)
-			}
+	return walkTrace(s, instrs, isComplete, s.context.Overflow, s.context.Loops)
+}
+
+func (s *Z3Solver) begin(i int, instr ssa.Instruction) {
+	s.pos = i
+}
+
+func (s *Z3Solver) bindTuple(v ssa.Value, elems []ssa.Value) {
+	s.tuples[v] = elems
+}
 
-		case *ssa.BinOp:
-			var err error
-			s.asts[instr], err = s.binop(instr)
-			if err != nil {
-				log.Error.Print(err)
+func (s *Z3Solver) loadInstr(instr, next ssa.Instruction) (callAction, error) {
+	switch instr := instr.(type) {
+	case *ssa.UnOp:
+		var err error
+		if instr.Op == token.MUL {
+			err = s.deref(instr)
+		} else {
+			s.asts[instr], err = s.unop(instr)
+		}
+		if err != nil {
+			log.Error.Print(err)
+		}
+	case *ssa.BinOp:
+		if cond := s.divisorCond(instr); cond != nil {
+			s.addDivZeroBranch(instr, cond)
+		}
+		var err error
+		s.asts[instr], err = s.binop(instr)
+		if err != nil {
+			log.Error.Print(err)
+		}
+	case *ssa.Call:
+		if instr.Call.IsInvoke() {
+			if s.invoke(instr, next) {
+				return callEnter, nil
 			}
-		case *ssa.Phi:
-			var v C.Z3_ast
-			for j, pred := range instr.Block().Preds {
-				if pred == prevBlock {
-					// TODO(ajalab) New variable?
-					v = s.get(instr.Edges[j])
-					break
-				}
+			break
+		}
+		switch fn := instr.Call.Value.(type) {
+		case *ssa.Function:
+			if s.callStrings(instr, fn) {
+				// The result is modelled, so the trace of the callee is skipped.
+				return callSkip, nil
 			}
-			s.asts[instr] = v
-		case *ssa.Call:
-			switch fn := instr.Call.Value.(type) {
-			case *ssa.Function:
-				// Is the called function recorded?
-				if i < len(instrs)-1 && instrs[i+1].Parent() == fn {
-					for j, arg := range instr.Call.Args {
-						log.Debug.Printf("call %v param%d %v <- %v", fn, j, fn.Params[j], arg)
-						s.asts[fn.Params[j]] = s.get(arg)
-						s.refs[fn.Params[j]] = s.refs[arg]
-					}
-					callStack = append(callStack, instr)
-				} else {
-					log.Debug.Printf("ignored function call %v (trace[i + 1]) = %v", instr, instrs[i+1])
+		case *ssa.Builtin:
+			switch fn.Name() {
+			case "len":
+				arg := instr.Call.Args[0]
+				if sl, ok := s.sliceOf(arg); ok {
+					s.asts[instr] = sl.len
+					break
 				}
-			case *ssa.Builtin:
-				switch fn.Name() {
-				case "len":
-					arg := instr.Call.Args[0]
-					ast := s.get(arg)
-					s.asts[instr] = z3MakeLen(s.ctx, ast, arg.Type())
+				if m, ok := s.maps[arg]; ok {
+					s.asts[instr] = m.len
+					break
 				}
-			default:
-				return errors.Errorf("call of function %s is not supported", fn)
-			}
-		case *ssa.Return:
-			// len(callStack) becomes 0 when instr.Parent() is init() or main() of
-			// the runner package.
-			if len(callStack) > 0 {
-				callInstr := callStack[len(callStack)-1]
-				// TODO(ajalab) Support multiple return values
-				switch len(instr.Results) {
-				case 0:
-				case 1:
-					s.asts[callInstr] = s.get(instr.Results[0])
-				default:
-					log.Error.Print("multiple return values are not supported")
+				ast := s.get(arg)
+				s.asts[instr] = z3MakeLen(s.ctx, ast, arg.Type())
+			case "cap":
+				arg := instr.Call.Args[0]
+				if sl, ok := s.sliceOf(arg); ok {
+					s.asts[instr] = sl.cap
 				}
-				callStack = callStack[:len(callStack)-1]
+			case "delete":
+				s.mapDelete(instr.Call.Args[0], instr.Call.Args[1])
 			}
-		case *ssa.If:
-			if s.get(instr.Cond) != nil {
-				thenBlock := instr.Block().Succs[0]
-				nextBlock := instrs[i+1].Block()
-				s.branches = append(s.branches, &BranchIf{
-					instr:     instr,
-					direction: thenBlock == nextBlock,
-				})
-			}
-		case *ssa.Store:
-			s.refs[instr.Addr] = instr.Val
-		case *ssa.FieldAddr:
-		}
-	}
-	// Execution was stopped due to panic
-	if !isComplete {
-		causeInstr := instrs[len(instrs)-1]
-		switch instr := causeInstr.(type) {
-		case *ssa.UnOp:
-			if instr.Op == token.MUL {
-				s.branches = append(s.branches, &BranchDeref{
-					instr:   instr,
-					success: false,
-					x:       instr.X,
-				})
-			}
-		case *ssa.FieldAddr:
-			s.branches = append(s.branches, &BranchDeref{
+		default:
+			return callDefault, errors.Errorf("call of function %s is not supported", fn)
+		}
+	case *ssa.Store:
+		s.store(instr.Addr, instr.Val)
+	case *ssa.FieldAddr:
+		s.fieldAddr(instr)
+	case *ssa.Field:
+		if fields, ok := s.fields[instr.X]; ok {
+			s.bind(instr, fields[instr.Field])
+		}
+	case *ssa.IndexAddr:
+		s.indexAddr(instr)
+	case *ssa.Index:
+		s.index(instr)
+	case *ssa.Slice:
+		s.slice(instr)
+	case *ssa.Alloc:
+		s.alloc(instr)
+	case *ssa.MakeSlice:
+		s.makeSlice(instr)
+	case *ssa.MakeMap:
+		s.makeMap(instr)
+	case *ssa.Lookup:
+		s.mapLookup(instr)
+	case *ssa.MapUpdate:
+		s.mapUpdate(instr)
+	case *ssa.Convert:
+		s.convert(instr)
+	case *ssa.ChangeType:
+		s.bind(instr, instr.X)
+	case *ssa.ChangeInterface:
+		s.bind(instr, instr.X)
+	case *ssa.TypeAssert:
+		s.typeAssert(instr)
+	case *ssa.Range:
+		s.rangeString(instr)
+	case *ssa.Next:
+		s.next(instr)
+	case *ssa.Extract:
+		if elems, ok := s.tuples[instr.Tuple]; ok && elems[instr.Index] != nil {
+			s.bind(instr, elems[instr.Index])
+		}
+	}
+	return callDefault, nil
+}
+
+func (s *Z3Solver) loadIf(instr *ssa.If, direction bool) {
+	cond := s.get(instr.Cond)
+	if cond == nil {
+		return
+	}
+	if !direction {
+		cond = C.Z3_mk_not(s.ctx, cond)
+	}
+	s.addBranch(&BranchIf{
+		instr:     instr,
+		direction: direction,
+	}, cond)
+}
+
+func (s *Z3Solver) loadPanic(causeInstr ssa.Instruction) error {
+	switch instr := causeInstr.(type) {
+	case *ssa.UnOp:
+		if instr.Op == token.MUL {
+			s.addDerefBranch(instr, instr.X, false)
+		}
+	case *ssa.FieldAddr:
+		s.addDerefBranch(instr, instr.X, false)
+	case *ssa.IndexAddr, *ssa.Index, *ssa.Lookup, *ssa.Slice:
+		if cond := s.boundsCond(instr.(ssa.Value)); cond != nil {
+			s.addBranch(&BranchBounds{
 				instr:   instr,
 				success: false,
-				x:       instr.X,
-			})
-		default:
-			return errors.Errorf("panic caused by %v@%s: %[1]T is not supported", instr, instr.Parent())
+			}, C.Z3_mk_not(s.ctx, cond))
+		}
+	case *ssa.TypeAssert:
+		if _, ok := s.ifaces[instr.X]; ok {
+			s.addBranch(&BranchTypeAssert{
+				instr:   instr,
+				success: false,
+			}, C.Z3_mk_not(s.ctx, s.typeCond(instr.X, instr.AssertedType)))
+		}
+	case *ssa.Call:
+		if instr.Call.IsInvoke() {
+			s.addNilInvokeBranch(instr, instr.Call.Value)
 		}
+	case *ssa.BinOp:
+		if cond := s.divisorCond(instr); cond != nil {
+			s.addBranch(&BranchDivZero{
+				instr:   instr,
+				success: false,
+			}, C.Z3_mk_not(s.ctx, cond))
+		}
+	default:
+		return errors.Errorf("panic caused by %v@%s: %[1]T is not supported", instr, instr.Parent())
 	}
 	return nil
 }
@@ -268,7 +486,7 @@ func (s *Z3Solver) Branch(i int) Branch {
 }
 
 func z3MakeAdd(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeAdd: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -277,6 +495,8 @@ func z3MakeAdd(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 	switch {
 	case info&types.IsInteger > 0:
 		return C.Z3_mk_bvadd(ctx, x, y)
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_add(ctx, z3MakeRoundingMode(ctx), x, y)
 	case info&types.IsString > 0:
 		args := []C.Z3_ast{x, y}
 		return C.Z3_mk_seq_concat(ctx, 2, &args[0])
@@ -287,7 +507,7 @@ func z3MakeAdd(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 }
 
 func z3MakeSub(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeSub: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -296,6 +516,8 @@ func z3MakeSub(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 	switch {
 	case info&types.IsInteger > 0:
 		return C.Z3_mk_bvsub(ctx, x, y)
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_sub(ctx, z3MakeRoundingMode(ctx), x, y)
 	default:
 		log.Error.Fatalf("z3MakeSub: not implemented: %T\n", ty)
 		panic("unimplemented")
@@ -303,7 +525,7 @@ func z3MakeSub(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 }
 
 func z3MakeMul(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeMul: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -312,6 +534,8 @@ func z3MakeMul(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 	switch {
 	case info&types.IsInteger > 0:
 		return C.Z3_mk_bvmul(ctx, x, y)
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_mul(ctx, z3MakeRoundingMode(ctx), x, y)
 	default:
 		log.Error.Fatalf("z3MakeMul: not implemented: %T\n", ty)
 		panic("unimplemented")
@@ -319,7 +543,7 @@ func z3MakeMul(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 }
 
 func z3MakeDiv(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeDiv: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -331,6 +555,8 @@ func z3MakeDiv(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 			return C.Z3_mk_bvudiv(ctx, x, y)
 		}
 		return C.Z3_mk_bvsdiv(ctx, x, y)
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_div(ctx, z3MakeRoundingMode(ctx), x, y)
 	default:
 		log.Error.Fatalf("z3MakeDiv: not implemented info: %v", basicTy.Kind())
 		panic("unimplemented")
@@ -338,7 +564,7 @@ func z3MakeDiv(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 }
 
 func z3MakeMod(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeMod: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -356,8 +582,16 @@ func z3MakeMod(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 	}
 }
 
+func z3MakeEq(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
+	if basicTy, ok := ty.Underlying().(*types.Basic); ok && basicTy.Info()&types.IsFloat > 0 {
+		// IEEE 754 equality: NaN is not equal to itself and +0 is equal to -0.
+		return C.Z3_mk_fpa_eq(ctx, x, y)
+	}
+	return C.Z3_mk_eq(ctx, x, y)
+}
+
 func z3MakeLt(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeLt: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -369,6 +603,11 @@ func z3MakeLt(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 			return C.Z3_mk_bvult(ctx, x, y)
 		}
 		return C.Z3_mk_bvslt(ctx, x, y)
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_lt(ctx, x, y)
+	case info&types.IsString > 0:
+		// Strings are compared lexicographically byte-wise.
+		return C.Z3_mk_str_lt(ctx, x, y)
 
 	default:
 		log.Error.Fatalf("z3MakeLt: not implemented info: %v", basicTy.Kind())
@@ -377,7 +616,7 @@ func z3MakeLt(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 }
 
 func z3MakeLe(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeLe: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -389,6 +628,10 @@ func z3MakeLe(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 			return C.Z3_mk_bvule(ctx, x, y)
 		}
 		return C.Z3_mk_bvsle(ctx, x, y)
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_leq(ctx, x, y)
+	case info&types.IsString > 0:
+		return C.Z3_mk_str_le(ctx, x, y)
 
 	default:
 		log.Error.Fatalf("z3MakeLe: not implemented info: %v", basicTy.Kind())
@@ -397,7 +640,7 @@ func z3MakeLe(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 }
 
 func z3MakeGt(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeGt: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -409,6 +652,10 @@ func z3MakeGt(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 			return C.Z3_mk_bvugt(ctx, x, y)
 		}
 		return C.Z3_mk_bvsgt(ctx, x, y)
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_gt(ctx, x, y)
+	case info&types.IsString > 0:
+		return C.Z3_mk_str_lt(ctx, y, x)
 
 	default:
 		log.Error.Fatalf("z3MakeGt: not implemented info: %v", basicTy.Kind())
@@ -417,7 +664,7 @@ func z3MakeGt(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 }
 
 func z3MakeGe(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
-	basicTy, ok := ty.(*types.Basic)
+	basicTy, ok := ty.Underlying().(*types.Basic)
 	if !ok {
 		log.Error.Fatalf("z3MakeGe: invalid type: %T\n", ty)
 		panic("unreachable")
@@ -429,6 +676,10 @@ func z3MakeGe(ctx C.Z3_context, x, y C.Z3_ast, ty types.Type) C.Z3_ast {
 			return C.Z3_mk_bvuge(ctx, x, y)
 		}
 		return C.Z3_mk_bvsge(ctx, x, y)
+	case info&types.IsFloat > 0:
+		return C.Z3_mk_fpa_geq(ctx, x, y)
+	case info&types.IsString > 0:
+		return C.Z3_mk_str_le(ctx, y, x)
 
 	default:
 		log.Error.Fatalf("z3MakeGe: not implemented info: %v", basicTy.Kind())
@@ -471,24 +722,173 @@ func z3MakeLen(ctx C.Z3_context, x C.Z3_ast, ty types.Type) C.Z3_ast {
 	panic("unimplemented")
 }
 
-func (s *Z3Solver) deref(instr *ssa.UnOp) (C.Z3_ast, error) {
+func (s *Z3Solver) deref(instr *ssa.UnOp) error {
 	ref, ok := s.refs[instr.X]
 	if !ok {
-		return nil, errors.Errorf("deref: reference does not exist for %s = %s (-> %s) in %s", instr.Name(), instr, instr.X, instr.Parent())
+		return errors.Errorf("deref: reference does not exist for %s = %s (-> %s) in %s", instr.Name(), instr, instr.X, instr.Parent())
+	}
+	s.checkNonNull(instr, instr.X)
+	if fields, ok := s.fields[ref]; ok {
+		s.fields[instr] = fields
+		return nil
 	}
 	ast := s.get(ref)
 	if ast == nil {
-		return nil, errors.Errorf("deref: reference ast does not exist: %v", instr.X)
+		return errors.Errorf("deref: reference ast does not exist: %v", instr.X)
+	}
+	s.asts[instr] = ast
+	return nil
+}
+
+// checkNonNull records a successful dereference of a symbolic pointer x by instr.
+func (s *Z3Solver) checkNonNull(instr ssa.Instruction, x ssa.Value) {
+	if _, ok := s.asts[x]; !ok {
+		// x is not a symbolic pointer (e.g., a local allocation).
+		return
+	}
+	if _, ok := s.nonnull[x]; !ok {
+		s.addDerefBranch(instr, x, true)
+		s.nonnull[x] = struct{}{}
+	}
+}
+
+// addDerefBranch appends a branch of dereference of a symbolic pointer x by instr.
+func (s *Z3Solver) addDerefBranch(instr ssa.Instruction, x ssa.Value, success bool) {
+	pointer, ok := s.asts[x]
+	if !ok || pointer == nil {
+		log.Error.Printf("corresponding AST for pointer dereference was not found: %+v", x)
+		return
+	}
+	sort := C.Z3_mk_int_sort(s.ctx)
+	zero := C.Z3_mk_unsigned_int(s.ctx, C.uint(0), sort)
+	cond := C.Z3_mk_eq(s.ctx, pointer, zero)
+	if success {
+		cond = C.Z3_mk_not(s.ctx, cond)
+	}
+	s.addBranch(&BranchDeref{
+		instr:   instr,
+		success: success,
+		x:       x,
+	}, cond)
+}
+
+// divisorCond returns the condition that the divisor of an integer division or remainder is not zero.
+// It returns nil if instr is not such an operation or its divisor is not symbolic.
+func (s *Z3Solver) divisorCond(instr *ssa.BinOp) C.Z3_ast {
+	if instr.Op != token.QUO && instr.Op != token.REM {
+		return nil
+	}
+	ty, ok := instr.Y.Type().Underlying().(*types.Basic)
+	if !ok || ty.Info()&types.IsInteger == 0 {
+		return nil
 	}
-	if _, ok := s.nonnull[instr.X]; !ok {
-		s.branches = append(s.branches, &BranchDeref{
-			instr:   instr,
-			success: true,
-			x:       instr.X,
-		})
-		s.nonnull[instr.X] = struct{}{}
+	y, ok := s.asts[instr.Y]
+	if !ok || y == nil {
+		return nil
+	}
+	return C.Z3_mk_not(s.ctx, C.Z3_mk_eq(s.ctx, y, z3MakeZero(s.ctx, ty)))
+}
+
+// addDivZeroBranch appends a branch of a successful division by instr if the condition is not trivial.
+func (s *Z3Solver) addDivZeroBranch(instr *ssa.BinOp, cond C.Z3_ast) {
+	if C.Z3_get_bool_value(s.ctx, C.Z3_simplify(s.ctx, cond)) != C.Z3_L_UNDEF {
+		return
+	}
+	s.addBranch(&BranchDivZero{
+		instr:   instr,
+		success: true,
+	}, cond)
+}
+
+// addBranch appends a branch with the condition that held when the branch was taken.
+// The condition is recorded at this time since the ASTs bound to SSA values
+// may be overwritten afterwards (e.g., in loops).
+func (s *Z3Solver) addBranch(b Branch, cond C.Z3_ast) {
+	s.branches = append(s.branches, b)
+	s.positions = append(s.positions, s.pos)
+	s.conds = append(s.conds, cond)
+}
+
+// fieldAddr loads the address of a struct field.
+// The address inherits the symbolic address of the struct since
+// they are nil at the same time.
+func (s *Z3Solver) fieldAddr(instr *ssa.FieldAddr) {
+	s.checkNonNull(instr, instr.X)
+	if ast, ok := s.asts[instr.X]; ok {
+		s.asts[instr] = ast
+		s.nonnull[instr] = struct{}{}
+	}
+	if ref, ok := s.refs[instr.X]; ok {
+		if fields, ok := s.fields[ref]; ok {
+			s.refs[instr] = fields[instr.Field]
+		}
+	}
+}
+
+// store updates the value referenced by addr with val.
+// If addr is the address of a struct field or an element,
+// the struct or the array that contains it is replaced with an updated copy.
+func (s *Z3Solver) store(addr, val ssa.Value) {
+	s.refs[addr] = val
+	if indexAddr, ok := addr.(*ssa.IndexAddr); ok {
+		s.storeElem(indexAddr, val)
+		return
+	}
+	fieldAddr, ok := addr.(*ssa.FieldAddr)
+	if !ok {
+		return
+	}
+	ref, ok := s.refs[fieldAddr.X]
+	if !ok {
+		return
+	}
+	fields, ok := s.fields[ref]
+	if !ok {
+		return
+	}
+	c := &clone{ref}
+	s.fields[c] = make([]ssa.Value, len(fields))
+	copy(s.fields[c], fields)
+	s.fields[c][fieldAddr.Field] = val
+	s.store(fieldAddr.X, c)
+}
+
+// bind makes dst have the same symbolic representation as src.
+func (s *Z3Solver) bind(dst, src ssa.Value) {
+	if c, ok := src.(*ssa.Const); ok && c.IsNil() {
+		// nil slices and maps are empty.
+		switch c.Type().Underlying().(type) {
+		case *types.Slice:
+			zero := z3MakeIntNumeral(s.ctx, 0)
+			s.slices[dst] = s.newZeroSlice(c.Type(), zero, zero)
+			return
+		case *types.Map:
+			if m, ok := s.newEmptyMap(c.Type()); ok {
+				s.maps[dst] = m
+			}
+			return
+		}
+	}
+	if fields, ok := s.fields[src]; ok {
+		s.fields[dst] = fields
+		return
+	}
+	if sl, ok := s.slices[src]; ok {
+		s.slices[dst] = sl
+		return
+	}
+	if m, ok := s.maps[src]; ok {
+		// Maps are reference types, so dst shares the updates with src.
+		s.maps[dst] = m
+		return
+	}
+	if iface, ok := s.ifaces[src]; ok {
+		s.ifaces[dst] = iface
+	}
+	s.asts[dst] = s.get(src)
+	if ref, ok := s.refs[src]; ok {
+		s.refs[dst] = ref
 	}
-	return ast, nil
 }
 
 func (s *Z3Solver) unop(instr *ssa.UnOp) (C.Z3_ast, error) {
@@ -498,6 +898,9 @@ func (s *Z3Solver) unop(instr *ssa.UnOp) (C.Z3_ast, error) {
 	}
 	switch instr.Op {
 	case token.SUB:
+		if basicTy, ok := instr.X.Type().Underlying().(*types.Basic); ok && basicTy.Info()&types.IsFloat > 0 {
+			return C.Z3_mk_fpa_neg(s.ctx, x), nil
+		}
 		return C.Z3_mk_bvneg(s.ctx, x), nil
 	case token.NOT:
 		return C.Z3_mk_not(s.ctx, x), nil
@@ -519,6 +922,12 @@ func (s *Z3Solver) binop(instr *ssa.BinOp) (C.Z3_ast, error) {
 	if y == nil {
 		return nil, errors.Errorf("binop: right operand is not registered: %s = %s in %s", instr.Name(), instr, instr.Parent())
 	}
+	if _, ok := ty.Underlying().(*types.Interface); ok {
+		// Only comparisons with nil are supported since the ASTs of interface values are their type IDs.
+		if !isNilConst(instr.X) && !isNilConst(instr.Y) {
+			return nil, errors.Errorf("binop: comparison of non-nil interface values is not supported: %s = %s in %s", instr.Name(), instr, instr.Parent())
+		}
+	}
 	args := []C.Z3_ast{x, y}
 	switch instr.Op {
 	case token.ADD:
@@ -532,9 +941,9 @@ func (s *Z3Solver) binop(instr *ssa.BinOp) (C.Z3_ast, error) {
 	case token.REM:
 		return z3MakeMod(s.ctx, x, y, ty), nil
 	case token.EQL:
-		return C.Z3_mk_eq(s.ctx, x, y), nil
+		return z3MakeEq(s.ctx, x, y, ty), nil
 	case token.NEQ:
-		return C.Z3_mk_not(s.ctx, C.Z3_mk_eq(s.ctx, x, y)), nil
+		return C.Z3_mk_not(s.ctx, z3MakeEq(s.ctx, x, y, ty)), nil
 	case token.LSS:
 		return z3MakeLt(s.ctx, x, y, ty), nil
 	case token.LEQ:
@@ -554,7 +963,7 @@ func (s *Z3Solver) binop(instr *ssa.BinOp) (C.Z3_ast, error) {
 	case token.SHL:
 		fallthrough
 	case token.SHR:
-		return z3MakeShift(s.ctx, x, y, instr.X.Type().(*types.Basic).Info(), instr.Op), nil
+		return z3MakeShift(s.ctx, x, y, instr.X.Type().Underlying().(*types.Basic).Info(), instr.Op), nil
 	case token.LAND:
 		return C.Z3_mk_and(s.ctx, 2, &args[0]), nil
 	case token.LOR:
@@ -564,6 +973,12 @@ func (s *Z3Solver) binop(instr *ssa.BinOp) (C.Z3_ast, error) {
 	}
 }
 
+// isNilConst reports whether v is the constant nil.
+func isNilConst(v ssa.Value) bool {
+	c, ok := v.(*ssa.Const)
+	return ok && c.IsNil()
+}
+
 func (s *Z3Solver) get(v ssa.Value) C.Z3_ast {
 	switch v := v.(type) {
 	case *ssa.Const:
@@ -578,7 +993,7 @@ func (s *Z3Solver) get(v ssa.Value) C.Z3_ast {
 }
 
 func (s *Z3Solver) getConstAST(v *ssa.Const) C.Z3_ast {
-	switch ty := v.Type().(type) {
+	switch ty := v.Type().Underlying().(type) {
 	case *types.Basic:
 		info := ty.Info()
 		switch {
@@ -588,17 +1003,19 @@ func (s *Z3Solver) getConstAST(v *ssa.Const) C.Z3_ast {
 			}
 			return C.Z3_mk_false(s.ctx)
 		case info&types.IsInteger > 0:
-			size := sizeOfBasicKind(ty.Kind())
+			sort := C.Z3_mk_bv_sort(s.ctx, C.uint(sizeOfBasicKind(ty.Kind())))
 			if info&types.IsUnsigned > 0 {
-				sort := C.Z3_mk_bv_sort(s.ctx, C.uint(size))
-				return C.Z3_mk_unsigned_int(s.ctx, C.uint(v.Uint64()), sort)
+				return C.Z3_mk_unsigned_int64(s.ctx, C.uint64_t(v.Uint64()), sort)
 			}
-			sort := C.Z3_mk_bv_sort(s.ctx, C.uint(size))
-			return C.Z3_mk_int(s.ctx, C.int(v.Int64()), sort)
+			return C.Z3_mk_int64(s.ctx, C.int64_t(v.Int64()), sort)
+		case info&types.IsFloat > 0:
+			f, _ := constant.Float64Val(v.Value)
+			return C.Z3_mk_fpa_numeral_double(s.ctx, C.double(f), newBasicSort(s.ctx, ty))
 		case info&types.IsString > 0:
-			return C.Z3_mk_string(s.ctx, C.CString(constant.StringVal(v.Value)))
+			return z3MakeString(s.ctx, constant.StringVal(v.Value))
 		}
-	case *types.Pointer:
+	case *types.Pointer, *types.Interface:
+		// nil pointers and interfaces are represented as the address 0.
 		if v.Value == nil {
 			sort := C.Z3_mk_int_sort(s.ctx)
 			return C.Z3_mk_unsigned_int(s.ctx, C.uint(0), sort)
@@ -613,68 +1030,83 @@ func (s *Z3Solver) Branches() []Branch {
 	return s.branches
 }
 
-func (s *Z3Solver) getBranchAST(branch Branch, negate bool) (C.Z3_ast, error) {
-	switch b := branch.(type) {
-	case *BranchIf:
-		cond := s.get(b.instr.Cond)
-		if cond == nil {
-			return nil, errors.Errorf("corresponding AST for branching condition was not found: %+v in %v",
-				branch.Instr(),
-				b.Instr().Parent(),
-			)
-		}
-		if (!negate && !b.direction) || (negate && b.direction) {
-			cond = C.Z3_mk_not(s.ctx, cond)
-		}
-		return cond, nil
-	case *BranchDeref:
-		pointer := s.get(b.x)
-		if pointer == nil {
-			return nil, errors.Errorf("corresponding AST for pointer dereference was not found: %+v", b.x)
-		}
-		sort := C.Z3_mk_int_sort(s.ctx)
-		zero := C.Z3_mk_unsigned_int(s.ctx, C.uint(0), sort)
-		cond := C.Z3_mk_eq(s.ctx, pointer, zero)
-		if negate && !b.success || !negate && b.success {
-			cond = C.Z3_mk_not(s.ctx, cond)
-		}
-		return cond, nil
+// Positions returns the indices of the instructions in the trace at which the branches were taken.
+func (s *Z3Solver) Positions() []int {
+	return s.positions
+}
 
-	default:
-		panic("unimplemented")
+func (s *Z3Solver) getBranchAST(i int, negate bool) C.Z3_ast {
+	cond := s.conds[i]
+	if negate {
+		if neg, ok := s.negations[i]; ok {
+			return neg
+		}
+		cond = C.Z3_mk_not(s.ctx, cond)
 	}
+	return cond
 }
 
 // Solve solves the assertions and returns concrete values for symbols.
 // The condition to solve is p_0 /\ p_1 /\ ... /\ p_(k-1) /\ not(a_k)
 // where p_i is a predicate of the i-th branching instruction and k = negate.
+//
+// Only the constraints that depend on not(a_k) are solved.
+// The solutions for the symbols not involved in them are nil,
+// which means that the symbols should keep the values in the current run.
+// The results are cached in the context, and the constraints are kept in the solver
+// so that subsequent queries can share them.
 func (s *Z3Solver) Solve(negate int) ([]Solution, error) {
-	solver := C.Z3_mk_solver(s.ctx)
-	C.Z3_solver_inc_ref(s.ctx, solver)
-	defer C.Z3_solver_dec_ref(s.ctx, solver)
-
+	constraints := make([]C.Z3_ast, 0, len(s.axioms)+negate)
+	constraints = append(constraints, s.axioms...)
 	for i := 0; i < negate; i++ {
-		branch := s.branches[i]
-		cond, err := s.getBranchAST(branch, false)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to solve constraints")
+		constraints = append(constraints, s.getBranchAST(i, false))
+	}
+	return s.solve(constraints, s.getBranchAST(negate, true))
+}
+
+// solve solves the constraints with the goal, where only the constraints relevant to the goal are used.
+func (s *Z3Solver) solve(constraints []C.Z3_ast, goal C.Z3_ast) ([]Solution, error) {
+	constraints, involved := s.relevant(constraints, goal)
+
+	key := s.queryKey(append(constraints, goal))
+	if result, ok := s.context.cache[key]; ok {
+		log.Debug.Printf("query cache hit (sat: %t)", result.sat)
+		if !result.sat {
+			return nil, UnsatError{}
 		}
-		C.Z3_solver_assert(s.ctx, solver, cond)
+		return result.solutions, nil
 	}
 
-	negBranch := s.branches[negate]
-	negCond, err := s.getBranchAST(negBranch, true)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to solve constraints")
+	if s.solver == nil {
+		s.solver = C.Z3_mk_solver(s.ctx)
+		C.Z3_solver_inc_ref(s.ctx, s.solver)
 	}
-	C.Z3_solver_assert(s.ctx, solver, negCond)
+	solver := s.solver
+	s.setTimeout(s.context.Timeout)
 
-	// fmt.Fprintf(os.Stderr, "solver\n%s\n", C.GoString(C.Z3_solver_to_string(s.ctx, solver)))
+	// Reuse the constraints asserted by the previous queries.
+	common := 0
+	for common < len(s.asserted) && common < len(constraints) && s.asserted[common] == constraints[common] {
+		common++
+	}
+	if len(s.asserted) > common {
+		C.Z3_solver_pop(s.ctx, solver, C.uint(len(s.asserted)-common))
+		s.asserted = s.asserted[:common]
+	}
+	for _, c := range constraints[common:] {
+		C.Z3_solver_push(s.ctx, solver)
+		C.Z3_solver_assert(s.ctx, solver, c)
+		s.asserted = append(s.asserted, c)
+	}
+	C.Z3_solver_push(s.ctx, solver)
+	defer C.Z3_solver_pop(s.ctx, solver, 1)
+	C.Z3_solver_assert(s.ctx, solver, goal)
 
 	result := C.Z3_solver_check(s.ctx, solver)
 
 	switch result {
 	case C.Z3_L_FALSE:
+		s.context.cache[key] = queryResult{sat: false}
 		return nil, UnsatError{}
 	case C.Z3_L_TRUE:
 		m := C.Z3_solver_get_model(s.ctx, solver)
@@ -682,20 +1114,53 @@ func (s *Z3Solver) Solve(negate int) ([]Solution, error) {
 			C.Z3_model_inc_ref(s.ctx, m)
 			defer C.Z3_model_dec_ref(s.ctx, m)
 		}
-		// fmt.Fprintf(os.Stderr, "model\n%s\n", C.GoString(C.Z3_model_to_string(s.ctx, m)))
-		solutions, err := s.getSolutions(m)
+		if s.context.Readable {
+			if rm := s.readableModel(append(constraints, goal), involved); rm != nil {
+				defer C.Z3_model_dec_ref(s.ctx, rm)
+				m = rm
+			}
+		}
+		solutions, err := s.getSolutions(m, involved)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get values from a model: %s", C.GoString(C.Z3_model_to_string(s.ctx, m)))
 		}
+		s.context.cache[key] = queryResult{sat: true, solutions: solutions}
 		return solutions, nil
 	default:
-		return nil, errors.Errorf("failed to solve: %s", C.GoString(C.Z3_solver_to_string(s.ctx, solver)))
+		// The result is not cached since it may be decided with a longer time limit.
+		return nil, UnknownError{Reason: C.GoString(C.Z3_solver_get_reason_unknown(s.ctx, solver))}
 	}
 }
 
-func (s *Z3Solver) getSolutions(m C.Z3_model) ([]Solution, error) {
+// setTimeout sets the time limit of the queries to the Z3 solver.
+func (s *Z3Solver) setTimeout(timeout time.Duration) {
+	params := s.timeoutParams(timeout)
+	defer C.Z3_params_dec_ref(s.ctx, params)
+	C.Z3_solver_set_params(s.ctx, s.solver, params)
+}
+
+// timeoutParams returns the parameters that set the time limit, which must be released by the caller.
+func (s *Z3Solver) timeoutParams(timeout time.Duration) C.Z3_params {
+	params := C.Z3_mk_params(s.ctx)
+	C.Z3_params_inc_ref(s.ctx, params)
+	// Z3 takes the timeout in milliseconds, where the maximum value means no limit.
+	ms := C.uint(math.MaxUint32)
+	if timeout > 0 && timeout/time.Millisecond < math.MaxUint32 {
+		ms = C.uint(timeout / time.Millisecond)
+		if ms == 0 {
+			ms = 1
+		}
+	}
+	C.Z3_params_set_uint(s.ctx, params, z3MkStringSymbol(s.ctx, "timeout"), ms)
+	return params
+}
+
+func (s *Z3Solver) getSolutions(m C.Z3_model, involved []bool) ([]Solution, error) {
 	solutions := make([]Solution, len(s.symbols))
 	for i, symbol := range s.symbols {
+		if !involved[i] {
+			continue
+		}
 		var err error
 		solutions[i], err = s.getSolutionFromModel(m, symbol)
 		if err != nil {
@@ -707,7 +1172,10 @@ func (s *Z3Solver) getSolutions(m C.Z3_model) ([]Solution, error) {
 
 func (s *Z3Solver) getASTFromModel(m C.Z3_model, v ssa.Value) (C.Z3_ast, error) {
 	var result C.Z3_ast
-	ast := s.asts[v]
+	ast, found := s.asts[v]
+	if !found || ast == nil {
+		return nil, errors.Errorf("corresponding Z3 AST was not found for %v", v)
+	}
 	ok := C.Z3_model_eval(s.ctx, m, ast, C.bool(true), &result)
 	if !C.bool(ok) {
 		return nil, errors.Errorf("failed to extract a concrete AST for %v from the model", v)
@@ -716,6 +1184,28 @@ func (s *Z3Solver) getASTFromModel(m C.Z3_model, v ssa.Value) (C.Z3_ast, error)
 }
 
 func (s *Z3Solver) getSolutionFromModel(m C.Z3_model, v ssa.Value) (Solution, error) {
+	if fields, ok := s.fields[v]; ok {
+		sols := make([]Solution, len(fields))
+		for i, field := range fields {
+			sol, _ := s.getSolutionFromModel(m, field)
+			if sol == nil {
+				sol = Indefinite{ty: field.Type()}
+			}
+			sols[i] = sol
+		}
+		return Definite{ty: v.Type(), value: sols}, nil
+	}
+
+	if sl, ok := s.slices[v]; ok {
+		return s.getSliceSolution(m, v.Type(), sl)
+	}
+	if mAST, ok := s.maps[v]; ok {
+		return s.getMapSolution(m, v.Type(), mAST)
+	}
+	if iface, ok := s.ifaces[v]; ok {
+		return s.getIfaceSolution(m, v, iface)
+	}
+
 	ast, err := s.getASTFromModel(m, v)
 	if err != nil {
 		return nil, err
@@ -723,52 +1213,7 @@ func (s *Z3Solver) getSolutionFromModel(m C.Z3_model, v ssa.Value) (Solution, er
 	ty := v.Type().Underlying()
 	switch ty := ty.(type) {
 	case *types.Basic:
-		info := ty.Info()
-		switch {
-		case info&types.IsInteger > 0:
-			var u C.uint64_t
-			if ok := bool(C.Z3_get_numeral_uint64(s.ctx, ast, &u)); !ok {
-				return nil, errors.Errorf("Z3_get_numeral_uint64: could not get an uint64 representation of the AST")
-			}
-			sol := Definite{ty: ty}
-			switch ty.Kind() {
-			case types.Int:
-				sol.value = int(u)
-			case types.Int8:
-				sol.value = int8(u)
-			case types.Int16:
-				sol.value = int16(u)
-			case types.Int32:
-				sol.value = int32(u)
-			case types.Int64:
-				sol.value = int64(u)
-			case types.Uint:
-				sol.value = uint(u)
-			case types.Uint8:
-				sol.value = uint8(u)
-			case types.Uint16:
-				sol.value = uint16(u)
-			case types.Uint32:
-				sol.value = uint32(u)
-			case types.Uint64:
-				sol.value = uint64(u)
-			default:
-				return nil, errors.Errorf("not supported integer: %v (%v)", ty, ty.Kind())
-			}
-			return sol, nil
-		case info&types.IsBoolean > 0:
-			b := C.Z3_get_bool_value(s.ctx, ast)
-			return Definite{
-				ty:    ty,
-				value: b == C.Z3_L_TRUE,
-			}, nil
-		case info&types.IsString > 0:
-			s, _ := strconv.Unquote(fmt.Sprintf(`"%s"`, C.GoString(C.Z3_get_string(s.ctx, ast))))
-			return Definite{
-				ty:    ty,
-				value: s,
-			}, nil
-		}
+		return s.getBasicSolution(ast, ty)
 	case *types.Pointer:
 		var i C.int
 		if ok := bool(C.Z3_get_numeral_int(s.ctx, ast, &i)); !ok {
@@ -794,35 +1239,60 @@ func (s *Z3Solver) getSolutionFromModel(m C.Z3_model, v ssa.Value) (Solution, er
 	return nil, errors.Errorf("type %v is not supported", ty)
 }
 
-func sizeOfBasicKind(k types.BasicKind) uint {
-	switch k {
-	case types.Int:
-		fallthrough
-	case types.Uint:
-		return strconv.IntSize
-	case types.Int8:
-		fallthrough
-	case types.Uint8:
-		return 8
-	case types.Int16:
-		fallthrough
-	case types.Uint16:
-		return 16
-	case types.Int32:
-		fallthrough
-	case types.Uint32:
-		return 32
-	case types.Int64:
-		fallthrough
-	case types.Uint64:
-		return 64
+// getBasicSolution returns a solution of basic type ty from an evaluated AST.
+func (s *Z3Solver) getBasicSolution(ast C.Z3_ast, ty *types.Basic) (Solution, error) {
+	info := ty.Info()
+	switch {
+	case info&types.IsInteger > 0:
+		var u C.uint64_t
+		if ok := bool(C.Z3_get_numeral_uint64(s.ctx, ast, &u)); !ok {
+			return nil, errors.Errorf("Z3_get_numeral_uint64: could not get an uint64 representation of the AST")
+		}
+		return integerSolution(uint64(u), ty)
+	case info&types.IsFloat > 0:
+		f, err := s.getFloat(ast)
+		if err != nil {
+			return nil, err
+		}
+		if ty.Kind() == types.Float32 {
+			return Definite{ty: ty, value: float32(f)}, nil
+		}
+		return Definite{ty: ty, value: f}, nil
+	case info&types.IsBoolean > 0:
+		b := C.Z3_get_bool_value(s.ctx, ast)
+		return Definite{
+			ty:    ty,
+			value: b == C.Z3_L_TRUE,
+		}, nil
+	case info&types.IsString > 0:
+		var n C.uint
+		str := C.Z3_get_lstring(s.ctx, ast, &n)
+		return Definite{
+			ty:    ty,
+			value: C.GoStringN(str, C.int(n)),
+		}, nil
 	}
-	return 0
+	return nil, errors.Errorf("type %v is not supported", ty)
 }
 
-// UnsatError is an error describing that Z3 constraints were unsatisfied.
-type UnsatError struct{}
-
-func (ue UnsatError) Error() string {
-	return "unsat"
+// getFloat returns the floating-point value of a numeral AST.
+func (s *Z3Solver) getFloat(ast C.Z3_ast) (float64, error) {
+	switch {
+	case bool(C.Z3_fpa_is_numeral_nan(s.ctx, ast)):
+		return math.NaN(), nil
+	case bool(C.Z3_fpa_is_numeral_inf(s.ctx, ast)):
+		if bool(C.Z3_fpa_is_numeral_negative(s.ctx, ast)) {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	}
+	bits := C.Z3_simplify(s.ctx, C.Z3_mk_fpa_to_ieee_bv(s.ctx, ast))
+	var u C.uint64_t
+	if ok := bool(C.Z3_get_numeral_uint64(s.ctx, bits, &u)); !ok {
+		return 0, errors.Errorf("Z3_get_numeral_uint64: could not get an IEEE 754 representation of the AST")
+	}
+	if C.Z3_get_bv_sort_size(s.ctx, C.Z3_get_sort(s.ctx, bits)) == 32 {
+		return float64(math.Float32frombits(uint32(u))), nil
+	}
+	return math.Float64frombits(uint64(u)), nil
 }
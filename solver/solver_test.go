@@ -0,0 +1,121 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const solverTestSrc = `package p
+
+func Chain(x, y, z int) int {
+	if x > 0 {
+		if y > x {
+			if y > 0 {
+				if z > y {
+					if x+y+z == 30 {
+						return 1
+					}
+				}
+			}
+		}
+	}
+	return 0
+}
+`
+
+// pathTrace returns the trace of fn that takes the then block of the i-th if if directions[i] is true
+// and the else block otherwise.
+func pathTrace(fn *ssa.Function, directions ...bool) []ssa.Instruction {
+	var instrs []ssa.Instruction
+	b := fn.Blocks[0]
+	for {
+		instrs = append(instrs, b.Instrs...)
+		switch instrs[len(instrs)-1].(type) {
+		case *ssa.If:
+			if directions[0] {
+				b = b.Succs[0]
+			} else {
+				b = b.Succs[1]
+			}
+			directions = directions[1:]
+		case *ssa.Jump:
+			b = b.Succs[0]
+		default:
+			return instrs
+		}
+	}
+}
+
+// TestIncrementalSolve solves the branches of a trace in non-monotonic order with one solver,
+// which pops and pushes the asserted constraints between the queries,
+// and checks each result against that of a fresh solver.
+func TestIncrementalSolve(t *testing.T) {
+	fn := buildTestPackage(t, solverTestSrc).Func("Chain")
+	symbols := []ssa.Value{fn.Params[0], fn.Params[1], fn.Params[2]}
+	directions := []bool{true, true, true, true, false}
+	instrs := pathTrace(fn, directions...)
+	// The values of the run, which are kept by nil solutions.
+	current := []int{1, 2, 3}
+	// taken returns the directions of the branches taken with x, y, and z.
+	taken := func(x, y, z int) []bool {
+		return []bool{x > 0, y > x, y > 0, z > y, x+y+z == 30}
+	}
+
+	context := NewZ3Context()
+	defer context.Close()
+	s, err := CreateZ3Solver(context, symbols, nil, instrs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if n := len(s.Branches()); n != len(directions) {
+		t.Fatalf("%d branches, want %d", n, len(directions))
+	}
+
+	for _, negate := range []int{3, 1, 4, 2, 0} {
+		freshContext := NewZ3Context()
+		fresh, err := CreateZ3Solver(freshContext, symbols, nil, instrs, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		freshSols, freshErr := fresh.Solve(negate)
+		fresh.Close()
+		freshContext.Close()
+
+		sols, err := s.Solve(negate)
+		if err != freshErr {
+			t.Errorf("negate %d: incremental %v, fresh %v", negate, err, freshErr)
+			continue
+		}
+		if negate == 2 {
+			// y > x > 0 implies y > 0.
+			if err != (UnsatError{}) {
+				t.Errorf("negate %d: expected unsat, actual %v", negate, err)
+			}
+			continue
+		}
+		for name, sols := range map[string][]Solution{"incremental": sols, "fresh": freshSols} {
+			checkSolutions(t, fmt.Sprintf("negate %d: %s", negate, name), sols, err, func(values []interface{}) bool {
+				xyz := make([]int, 3)
+				for i, v := range values {
+					xyz[i] = current[i]
+					if v != nil {
+						xyz[i] = v.(int)
+					}
+				}
+				got := taken(xyz[0], xyz[1], xyz[2])
+				for i := 0; i < negate; i++ {
+					if got[i] != directions[i] {
+						return false
+					}
+				}
+				return got[negate] != directions[negate]
+			})
+		}
+	}
+}
@@ -0,0 +1,323 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <stdlib.h>
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"go/constant"
+	"go/types"
+	"strconv"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// stringIter is the state of a range loop over a string.
+// pos is the byte offset of the next rune and rest is the suffix of the string from pos.
+type stringIter struct {
+	rest C.Z3_ast
+	pos  C.Z3_ast
+}
+
+// isString reports whether ty is a string type.
+func isString(ty types.Type) bool {
+	basicTy, ok := ty.Underlying().(*types.Basic)
+	return ok && basicTy.Info()&types.IsString > 0
+}
+
+// tryGet returns the AST of v, or nil if v is not symbolic.
+// Unlike get, it does not report an error for values that are not registered.
+func (s *Z3Solver) tryGet(v ssa.Value) C.Z3_ast {
+	if c, ok := v.(*ssa.Const); ok {
+		return s.getConstAST(c)
+	}
+	return s.asts[v]
+}
+
+// stringLen returns the length of a string str as an int.
+func (s *Z3Solver) stringLen(str C.Z3_ast) C.Z3_ast {
+	return C.Z3_mk_int2bv(s.ctx, strconv.IntSize, C.Z3_mk_seq_length(s.ctx, str))
+}
+
+// stringSliceBounds returns the (low, high) indices of the slice operation instr on a string str.
+func (s *Z3Solver) stringSliceBounds(instr *ssa.Slice, str C.Z3_ast) (C.Z3_ast, C.Z3_ast, bool) {
+	bound := func(v ssa.Value, def C.Z3_ast) C.Z3_ast {
+		if v == nil {
+			return def
+		}
+		ast := s.tryGet(v)
+		if ast == nil {
+			return nil
+		}
+		return z3MakeIntCast(s.ctx, ast, v.Type())
+	}
+	low := bound(instr.Low, z3MakeIntNumeral(s.ctx, 0))
+	high := bound(instr.High, s.stringLen(str))
+	return low, high, low != nil && high != nil
+}
+
+// stringBoundsCond returns the condition that the bounds check of indexing (*ssa.Lookup)
+// or slicing a string succeeds.
+// It returns nil if the operands of v are not symbolic.
+func (s *Z3Solver) stringBoundsCond(v ssa.Value) C.Z3_ast {
+	switch v := v.(type) {
+	case *ssa.Lookup:
+		str, i := s.tryGet(v.X), s.tryGet(v.Index)
+		if str == nil || i == nil {
+			return nil
+		}
+		// Negative indices are regarded as large unsigned integers.
+		return C.Z3_mk_bvult(s.ctx, z3MakeIntCast(s.ctx, i, v.Index.Type()), s.stringLen(str))
+	case *ssa.Slice:
+		str := s.tryGet(v.X)
+		if str == nil {
+			return nil
+		}
+		low, high, ok := s.stringSliceBounds(v, str)
+		if !ok {
+			return nil
+		}
+		args := []C.Z3_ast{
+			C.Z3_mk_bvule(s.ctx, low, high),
+			C.Z3_mk_bvule(s.ctx, high, s.stringLen(str)),
+		}
+		return C.Z3_mk_and(s.ctx, 2, &args[0])
+	}
+	return nil
+}
+
+// stringIndex loads an indexing of a string, which yields a byte.
+func (s *Z3Solver) stringIndex(instr *ssa.Lookup) {
+	cond := s.stringBoundsCond(instr)
+	if cond == nil {
+		return
+	}
+	s.addBoundsBranch(instr, cond)
+	i := z3MakeIntCast(s.ctx, s.tryGet(instr.Index), instr.Index.Type())
+	s.asts[instr] = s.byteAt(s.tryGet(instr.X), i)
+}
+
+// stringSlice loads a slice operation on a string.
+func (s *Z3Solver) stringSlice(instr *ssa.Slice) {
+	cond := s.stringBoundsCond(instr)
+	if cond == nil {
+		return
+	}
+	s.addBoundsBranch(instr, cond)
+	str := s.tryGet(instr.X)
+	low, high, _ := s.stringSliceBounds(instr, str)
+	s.asts[instr] = C.Z3_mk_seq_extract(s.ctx, str,
+		C.Z3_mk_bv2int(s.ctx, low, C.bool(false)),
+		C.Z3_mk_bv2int(s.ctx, C.Z3_mk_bvsub(s.ctx, high, low), C.bool(false)),
+	)
+}
+
+// decodeRune returns the first rune in str and its width in bytes,
+// which is given both as a mathematical integer and as a bit-vector of type int.
+// It follows utf8.DecodeRuneInString: an invalid encoding yields ("\uFFFD", 1).
+// Overlong encodings and surrogate halves are excluded by the ranges of the first two bytes.
+// The decoding is computed in integer arithmetic over the character codes
+// since conversions between integers and bit-vectors are expensive for the solver.
+func (s *Z3Solver) decodeRune(str C.Z3_ast) (C.Z3_ast, C.Z3_ast, C.Z3_ast) {
+	intSort := C.Z3_mk_int_sort(s.ctx)
+	num := func(n int) C.Z3_ast { return C.Z3_mk_int(s.ctx, C.int(n), intSort) }
+	toCode := s.stringFunc("str.to_code", "String")
+	b := make([]C.Z3_ast, 4)
+	for k := range b {
+		// Bytes are read at constant offsets, which is much cheaper for the solver than symbolic ones.
+		c := C.Z3_mk_seq_at(s.ctx, str, num(k))
+		b[k] = C.Z3_mk_app(s.ctx, toCode, 1, &c)
+	}
+	and := func(args ...C.Z3_ast) C.Z3_ast {
+		return C.Z3_mk_and(s.ctx, C.uint(len(args)), &args[0])
+	}
+	in := func(k, lo, hi int) C.Z3_ast {
+		return and(C.Z3_mk_ge(s.ctx, b[k], num(lo)), C.Z3_mk_le(s.ctx, b[k], num(hi)))
+	}
+	// second returns the condition on the second byte that depends on the first byte (see utf8.acceptRanges).
+	second := func(ranges map[int][2]int, lo, hi int) C.Z3_ast {
+		cond := in(1, 0x80, 0xBF)
+		for b0, r := range ranges {
+			cond = C.Z3_mk_ite(s.ctx, C.Z3_mk_eq(s.ctx, b[0], num(b0)), in(1, r[0], r[1]), cond)
+		}
+		return and(in(0, lo, hi), cond)
+	}
+	// bits returns (b[k] - base) * 2^shift, which equals (b[k] & ^base) << shift within the valid range.
+	bits := func(k, base, shift int) C.Z3_ast {
+		diff := []C.Z3_ast{b[k], num(base)}
+		args := []C.Z3_ast{C.Z3_mk_sub(s.ctx, 2, &diff[0]), num(1 << uint(shift))}
+		return C.Z3_mk_mul(s.ctx, 2, &args[0])
+	}
+	sum := func(args ...C.Z3_ast) C.Z3_ast {
+		return C.Z3_mk_add(s.ctx, C.uint(len(args)), &args[0])
+	}
+	decodings := []struct {
+		width int
+		valid C.Z3_ast
+		r     C.Z3_ast
+	}{
+		{
+			2,
+			second(nil, 0xC2, 0xDF),
+			sum(bits(0, 0xC0, 6), bits(1, 0x80, 0)),
+		},
+		{
+			3,
+			and(second(map[int][2]int{0xE0: {0xA0, 0xBF}, 0xED: {0x80, 0x9F}}, 0xE0, 0xEF), in(2, 0x80, 0xBF)),
+			sum(bits(0, 0xE0, 12), bits(1, 0x80, 6), bits(2, 0x80, 0)),
+		},
+		{
+			4,
+			and(second(map[int][2]int{0xF0: {0x90, 0xBF}, 0xF4: {0x80, 0x8F}}, 0xF0, 0xF4), in(2, 0x80, 0xBF), in(3, 0x80, 0xBF)),
+			sum(bits(0, 0xF0, 18), bits(1, 0x80, 12), bits(2, 0x80, 6), bits(3, 0x80, 0)),
+		},
+	}
+
+	r, width, widthInt := num(0xFFFD), num(1), z3MakeIntNumeral(s.ctx, 1)
+	l := C.Z3_mk_seq_length(s.ctx, str)
+	for _, d := range decodings {
+		// The bytes beyond the end of str are unspecified.
+		valid := and(d.valid, C.Z3_mk_le(s.ctx, num(d.width), l))
+		r = C.Z3_mk_ite(s.ctx, valid, d.r, r)
+		width = C.Z3_mk_ite(s.ctx, valid, num(d.width), width)
+		widthInt = C.Z3_mk_ite(s.ctx, valid, z3MakeIntNumeral(s.ctx, d.width), widthInt)
+	}
+	ascii := C.Z3_mk_lt(s.ctx, b[0], num(0x80))
+	r = C.Z3_mk_ite(s.ctx, ascii, b[0], r)
+	width = C.Z3_mk_ite(s.ctx, ascii, num(1), width)
+	widthInt = C.Z3_mk_ite(s.ctx, ascii, z3MakeIntNumeral(s.ctx, 1), widthInt)
+	return C.Z3_mk_int2bv(s.ctx, 32, r), width, widthInt
+}
+
+// rangeString loads the start of a range loop over a string.
+func (s *Z3Solver) rangeString(instr *ssa.Range) {
+	str := s.tryGet(instr.X)
+	if str == nil {
+		return
+	}
+	s.iters[instr] = &stringIter{
+		rest: str,
+		pos:  z3MakeIntNumeral(s.ctx, 0),
+	}
+}
+
+// next loads an iteration of a range loop over a string,
+// which yields a tuple (ok, index, rune).
+func (s *Z3Solver) next(instr *ssa.Next) {
+	it, ok := s.iters[instr.Iter]
+	if !ok || !instr.IsString {
+		return
+	}
+	r, width, widthInt := s.decodeRune(it.rest)
+	l := C.Z3_mk_seq_length(s.ctx, it.rest)
+	elems := []ssa.Value{&component{instr, 0}, &component{instr, 1}, &component{instr, 2}}
+	s.asts[elems[0]] = C.Z3_mk_gt(s.ctx, l, C.Z3_mk_int(s.ctx, 0, C.Z3_mk_int_sort(s.ctx)))
+	s.asts[elems[1]] = it.pos
+	s.asts[elems[2]] = r
+	s.tuples[instr] = elems
+	// The rest of the string is a fresh variable defined by rest = head + next, where len(head) = width.
+	// Word equations over fresh variables are much easier for the solver than nested extractions.
+	strSort := C.Z3_mk_string_sort(s.ctx)
+	head := z3MkFreshConst(s.ctx, "head", strSort)
+	next := z3MkFreshConst(s.ctx, "rest", strSort)
+	def := []C.Z3_ast{
+		C.Z3_mk_eq(s.ctx, it.rest, z3MakeConcat(s.ctx, []C.Z3_ast{head, next})),
+		C.Z3_mk_eq(s.ctx, C.Z3_mk_seq_length(s.ctx, head), width),
+	}
+	s.axioms = append(s.axioms, C.Z3_mk_implies(s.ctx, s.asts[elems[0]], C.Z3_mk_and(s.ctx, 2, &def[0])))
+	it.rest = next
+	it.pos = C.Z3_mk_bvadd(s.ctx, it.pos, widthInt)
+}
+
+// callStrings loads a call of a function in the strings package whose result is modelled by the solver.
+// It reports whether the call was modelled, in which case the trace of the callee should be skipped.
+func (s *Z3Solver) callStrings(instr *ssa.Call, fn *ssa.Function) bool {
+	if fn.Pkg == nil || fn.Pkg.Pkg.Path() != "strings" {
+		return false
+	}
+	args := make([]C.Z3_ast, len(instr.Call.Args))
+	for j, arg := range instr.Call.Args {
+		if args[j] = s.tryGet(arg); args[j] == nil {
+			return false
+		}
+	}
+	switch fn.Name() {
+	case "HasPrefix":
+		s.asts[instr] = C.Z3_mk_seq_prefix(s.ctx, args[1], args[0])
+	case "HasSuffix":
+		s.asts[instr] = C.Z3_mk_seq_suffix(s.ctx, args[1], args[0])
+	case "Contains":
+		s.asts[instr] = C.Z3_mk_seq_contains(s.ctx, args[0], args[1])
+	case "Index":
+		zero := C.Z3_mk_int(s.ctx, 0, C.Z3_mk_int_sort(s.ctx))
+		// seq.indexof returns -1 if substr is not present and 0 if substr is empty like strings.Index.
+		s.asts[instr] = C.Z3_mk_int2bv(s.ctx, strconv.IntSize, C.Z3_mk_seq_index(s.ctx, args[0], args[1], zero))
+	case "Split":
+		return s.split(instr, args[0])
+	default:
+		return false
+	}
+	return true
+}
+
+// split loads a call of strings.Split with a non-empty constant separator.
+// The result is a slice of fresh strings e_0, ..., e_(n-1) such that
+// str = e_0 + sep + e_1 + ... + sep + e_(n-1) and the first occurrence of sep after e_i is right after e_i.
+// n is bounded by maxSymbolicSliceLen.
+func (s *Z3Solver) split(instr *ssa.Call, str C.Z3_ast) bool {
+	c, ok := instr.Call.Args[1].(*ssa.Const)
+	if !ok {
+		return false
+	}
+	sepStr := constant.StringVal(c.Value)
+	if sepStr == "" {
+		return false
+	}
+	sep := z3MakeString(s.ctx, sepStr)
+	// The occurrence of sep right after e_i is the first one iff e_i + sep[:len(sep)-1] does not contain sep.
+	sepPrefix := z3MakeString(s.ctx, sepStr[:len(sepStr)-1])
+
+	intSort := z3MakeIntSort(s.ctx)
+	strSort := C.Z3_mk_string_sort(s.ctx)
+	n := z3MkFreshConst(s.ctx, "len(split)", intSort)
+	array := z3MkFreshConst(s.ctx, "split", C.Z3_mk_array_sort(s.ctx, intSort, strSort))
+	args := []C.Z3_ast{
+		C.Z3_mk_bvsge(s.ctx, n, z3MakeIntNumeral(s.ctx, 1)),
+		C.Z3_mk_bvsle(s.ctx, n, z3MakeIntNumeral(s.ctx, maxSymbolicSliceLen)),
+	}
+	s.axioms = append(s.axioms, C.Z3_mk_and(s.ctx, 2, &args[0]))
+
+	parts := make([]C.Z3_ast, 0, 2*maxSymbolicSliceLen-1)
+	for i := 0; i < maxSymbolicSliceLen; i++ {
+		e := z3MkFreshConst(s.ctx, "split-elem", strSort)
+		s.axioms = append(s.axioms, C.Z3_mk_eq(s.ctx, C.Z3_mk_select(s.ctx, array, z3MakeIntNumeral(s.ctx, i)), e))
+		if i > 0 {
+			parts = append(parts, sep)
+		}
+		parts = append(parts, e)
+
+		isLast := C.Z3_mk_eq(s.ctx, n, z3MakeIntNumeral(s.ctx, i+1))
+		last := []C.Z3_ast{
+			C.Z3_mk_eq(s.ctx, str, z3MakeConcat(s.ctx, parts)),
+			C.Z3_mk_not(s.ctx, C.Z3_mk_seq_contains(s.ctx, e, sep)),
+		}
+		s.axioms = append(s.axioms, C.Z3_mk_implies(s.ctx, isLast, C.Z3_mk_and(s.ctx, 2, &last[0])))
+		notLast := C.Z3_mk_bvslt(s.ctx, z3MakeIntNumeral(s.ctx, i+1), n)
+		s.axioms = append(s.axioms, C.Z3_mk_implies(s.ctx, notLast, C.Z3_mk_not(s.ctx,
+			C.Z3_mk_seq_contains(s.ctx, z3MakeConcat(s.ctx, []C.Z3_ast{e, sepPrefix}), sep),
+		)))
+	}
+	s.slices[instr] = sliceAST{
+		array:  array,
+		offset: z3MakeIntNumeral(s.ctx, 0),
+		len:    n,
+		cap:    n,
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+//go:build cgo && !noz3
+// +build cgo,!noz3
+
+package solver
+
+import (
+	/*
+		#include <z3.h>
+	*/
+	"C"
+)
+import (
+	"go/constant"
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// loadLoopExit adds the BranchLoop of the counting loop l, which has exited after the first iteration.
+// The value of the induction variable at the exit is a fresh constant x summarized by the conditions that
+// x is beyond Start (x > Start if incremented and x < Start if decremented), the distance between x and Start
+// is a multiple of Step, and the condition of the loop does not hold for x but holds for the value of the previous iteration.
+// They hold together iff the loop started from Start exits with x after at least two evaluations of the condition.
+// The branch asserts the summary, which leaves the trip count to the conditions of the later branches,
+// and its negation asserts that x is not the current value, which makes the loop exit after another number of iterations.
+func (s *Z3Solver) loadLoopExit(l *CountingLoop) {
+	if _, ok := l.Bound.(*ssa.Const); ok {
+		// The trip count is fixed.
+		return
+	}
+	bound, ok := s.asts[l.Bound]
+	if !ok {
+		return
+	}
+	current, ok := s.asts[l.Induction]
+	if !ok {
+		return
+	}
+	ty := l.Induction.Type()
+	start, step := s.get(l.Start), s.get(l.Step)
+	zero := s.get(ssa.NewConst(constant.MakeInt64(0), ty))
+	x := z3MkFreshConst(s.ctx, "exit("+l.Induction.Name()+")", C.Z3_get_sort(s.ctx, current))
+
+	holds := func(v C.Z3_ast) C.Z3_ast {
+		switch l.Op {
+		case token.LSS:
+			return z3MakeLt(s.ctx, v, bound, ty)
+		case token.LEQ:
+			return z3MakeLe(s.ctx, v, bound, ty)
+		case token.GTR:
+			return z3MakeGt(s.ctx, v, bound, ty)
+		case token.GEQ:
+			return z3MakeGe(s.ctx, v, bound, ty)
+		default:
+			return C.Z3_mk_not(s.ctx, z3MakeEq(s.ctx, v, bound, ty))
+		}
+	}
+	var beyond, distance, prev C.Z3_ast
+	if l.Decrement {
+		beyond = z3MakeLt(s.ctx, x, start, ty)
+		distance = z3MakeSub(s.ctx, start, x, ty)
+		prev = z3MakeAdd(s.ctx, x, step, ty)
+	} else {
+		beyond = z3MakeGt(s.ctx, x, start, ty)
+		distance = z3MakeSub(s.ctx, x, start, ty)
+		prev = z3MakeSub(s.ctx, x, step, ty)
+	}
+	conds := []C.Z3_ast{
+		beyond,
+		z3MakeEq(s.ctx, C.Z3_mk_bvurem(s.ctx, distance, step), zero, ty),
+		C.Z3_mk_not(s.ctx, holds(x)),
+		holds(prev),
+	}
+	summary := C.Z3_mk_and(s.ctx, C.uint(len(conds)), &conds[0])
+
+	other := []C.Z3_ast{summary, C.Z3_mk_not(s.ctx, z3MakeEq(s.ctx, x, current, ty))}
+	s.negations[len(s.branches)] = C.Z3_mk_and(s.ctx, 2, &other[0])
+	s.addBranch(&BranchLoop{instr: l.If}, summary)
+	s.asts[l.Induction] = x
+}
@@ -0,0 +1,174 @@
+package solver
+
+import (
+	"github.com/ajalab/congo/log"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// traceLoader is implemented by the solvers of the backends to load the instructions of a trace.
+// The control flow of the trace (φ-nodes, calls, returns, and ifs) is followed by walkTrace.
+type traceLoader interface {
+	// begin is called before the i-th instruction of the trace is loaded.
+	begin(i int, instr ssa.Instruction)
+	// bind makes dst have the same symbolic representation as src.
+	bind(dst, src ssa.Value)
+	// bindTuple makes the components of the tuple v have the representations of elems.
+	bindTuple(v ssa.Value, elems []ssa.Value)
+	// loadInstr loads instr, which is neither a φ-node, a return, nor an if.
+	// next is the next instruction of the trace, or nil if instr is the last one.
+	loadInstr(instr, next ssa.Instruction) (callAction, error)
+	// loadIf adds the branch of instr taken to the then block if direction is true and to the else block otherwise.
+	loadIf(instr *ssa.If, direction bool)
+	// loadLoopExit adds the BranchLoop of the counting loop l, which has exited after the first iteration,
+	// and binds the induction variable to its value at the exit solved from the trip count.
+	loadLoopExit(l *CountingLoop)
+	// addOverflow records the condition under which instr overflows.
+	addOverflow(instr ssa.Instruction)
+	// loadPanic adds the branch of instr, which caused a panic.
+	loadPanic(instr ssa.Instruction) error
+}
+
+// callAction is what walkTrace does with the trace of the callee of a call loaded by traceLoader.loadInstr.
+type callAction int
+
+const (
+	// callDefault makes walkTrace enter the callee if the call is a static call to a function that is traced.
+	callDefault callAction = iota
+	// callEnter makes walkTrace enter the callee, whose results are bound to the call on return.
+	// The loader has bound the arguments to the parameters.
+	callEnter
+	// callSkip makes walkTrace skip the trace of the callee since the result of the call is modelled.
+	callSkip
+)
+
+// walkTrace loads the path condition of the trace instrs with l.
+// If isComplete is false, the last instruction of the trace is regarded as the cause of a panic.
+// If overflow is true, the conditions of overflows are recorded as well (see mayOverflow).
+// If loops is not nil, the conditions of the counting loops it returns are summarized (see CountingLoop).
+func walkTrace(l traceLoader, instrs []ssa.Instruction, isComplete, overflow bool, loops Loops) error {
+	var currentBlock *ssa.BasicBlock
+	var prevBlock *ssa.BasicBlock
+	var callStack []*ssa.Call
+	// iterations counts the iterations of the counting loops from 1,
+	// which is reset when a loop is entered from outside.
+	iterations := make(map[loopInstance]int)
+
+	// If the trace is not complete, ignore the last instruction,
+	// which is a cause of failure.
+	n := len(instrs)
+	if !isComplete {
+		n = n - 1
+	}
+
+	for i := 0; i < n; i++ {
+		instr := instrs[i]
+		l.begin(i, instr)
+		block := instr.Block()
+		if currentBlock != block {
+			prevBlock = currentBlock
+			currentBlock = block
+			log.Debug.Printf("block: %v.%s", block.Parent(), block)
+		}
+		if loops != nil && instr == block.Instrs[0] && loops(block) != nil {
+			key := loopInstance{len(callStack), block}
+			// The header is reached from the loop through a back edge, whose source is dominated by the header.
+			if prevBlock != nil && block.Dominates(prevBlock) {
+				iterations[key]++
+			} else {
+				iterations[key] = 1
+			}
+		}
+		var next ssa.Instruction
+		if i+1 < len(instrs) {
+			next = instrs[i+1]
+		}
+
+		switch instr := instr.(type) {
+		case *ssa.Phi:
+			for j, pred := range instr.Block().Preds {
+				if pred == prevBlock {
+					l.bind(instr, instr.Edges[j])
+					break
+				}
+			}
+		case *ssa.Return:
+			// len(callStack) becomes 0 when instr.Parent() is init() or main() of
+			// the runner package.
+			if len(callStack) > 0 {
+				callInstr := callStack[len(callStack)-1]
+				switch len(instr.Results) {
+				case 0:
+				case 1:
+					l.bind(callInstr, instr.Results[0])
+				default:
+					// The results are bound to the components of the tuple,
+					// which are extracted by ssa.Extract in the caller.
+					elems := make([]ssa.Value, len(instr.Results))
+					for j, result := range instr.Results {
+						elems[j] = &component{callInstr, j}
+						l.bind(elems[j], result)
+					}
+					l.bindTuple(callInstr, elems)
+				}
+				callStack = callStack[:len(callStack)-1]
+			}
+		case *ssa.If:
+			// The direction is unknown if the trace was cut off by the budget of the interpreter.
+			if next == nil {
+				break
+			}
+			direction := instr.Block().Succs[0] == next.Block()
+			var cl *CountingLoop
+			if loops != nil {
+				cl = loops(instr.Block())
+			}
+			if cl == nil || cl.If != instr || iterations[loopInstance{len(callStack), instr.Block()}] <= 1 {
+				l.loadIf(instr, direction)
+			} else if !direction {
+				l.loadLoopExit(cl)
+			}
+		default:
+			action, err := l.loadInstr(instr, next)
+			if err != nil {
+				return err
+			}
+			call, _ := instr.(*ssa.Call)
+			switch action {
+			case callDefault:
+				if call == nil || call.Call.IsInvoke() {
+					break
+				}
+				fn, ok := call.Call.Value.(*ssa.Function)
+				if !ok {
+					break
+				}
+				// Is the called function recorded?
+				if next != nil && next.Parent() == fn {
+					for j, arg := range call.Call.Args {
+						log.Debug.Printf("call %v param%d %v <- %v", fn, j, fn.Params[j], arg)
+						l.bind(fn.Params[j], arg)
+					}
+					callStack = append(callStack, call)
+				} else {
+					log.Debug.Printf("ignored function call %v", call)
+				}
+			case callEnter:
+				callStack = append(callStack, call)
+			case callSkip:
+				for i < n-1 && instrs[i+1].Parent() != instr.Parent() {
+					i++
+				}
+			}
+		}
+		if overflow && mayOverflow(instr) {
+			l.addOverflow(instr)
+		}
+	}
+	// Execution was stopped due to panic
+	if !isComplete {
+		l.begin(len(instrs)-1, instrs[len(instrs)-1])
+		return l.loadPanic(instrs[len(instrs)-1])
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package congo
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+
+	"github.com/ajalab/congo/solver"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Path is a type that contains the trace of a run and its branches.
+type Path struct {
+	Branches []solver.Branch
+	// Bound is the number of leading branches that must not be negated.
+	// They have been fixed by the ancestors of the path.
+	Bound int
+	// Generation is the number of negations that led to the path.
+	Generation int
+	// NewBlocks is the number of blocks first covered by the run.
+	NewBlocks int
+	// NewEdges is the number of edges first covered by the run.
+	NewEdges int
+
+	// solutions are the values of the symbols given to the run.
+	solutions []solver.Solution
+	instrs    []ssa.Instruction
+	complete  bool
+}
+
+// Candidate is a type that represents a branch of a path to be negated.
+type Candidate struct {
+	Path  *Path
+	Index int
+}
+
+// Branch returns the branch to be negated.
+func (c Candidate) Branch() solver.Branch {
+	return c.Path.Branches[c.Index]
+}
+
+// Strategy is an interface of path-exploration strategies.
+// A strategy keeps a worklist of candidates collected from all the past runs
+// and selects the candidate to be negated next.
+type Strategy interface {
+	// Push adds a candidate to the worklist.
+	// The candidates of a path are pushed in the order of the branches.
+	Push(c Candidate)
+	// Pop removes the next candidate from the worklist and returns it.
+	// It returns false if the worklist is empty.
+	Pop() (Candidate, bool)
+}
+
+// NewStrategy returns a built-in strategy of the given name for the target function.
+// The available strategies are "dfs", "bfs", "generational", "random", and "directed".
+// metric is the coverage metric ("block" or "edge") by which the strategies prioritize branches.
+func NewStrategy(name string, target *ssa.Function, metric string) (Strategy, error) {
+	switch name {
+	case "dfs":
+		return &dfsStrategy{edges: metric == "edge", pathCoverage: newPathCoverage()}, nil
+	case "bfs":
+		return &bfsStrategy{}, nil
+	case "generational":
+		return &generationalStrategy{edges: metric == "edge"}, nil
+	case "random":
+		return &randomStrategy{rand: rand.New(rand.NewSource(1))}, nil
+	case "directed":
+		return newDirectedStrategy(target, metric == "edge"), nil
+	}
+	return nil, errors.Errorf("unknown strategy: %s", name)
+}
+
+// dfsStrategy is a depth-first search strategy.
+// It negates the deepest branch of the latest path whose untaken side has not been covered yet,
+// or the deepest branch of the latest path if there is no such branch.
+// If edges is true, a side of a branch is covered if the edge to it has been covered.
+type dfsStrategy struct {
+	stack []Candidate
+	edges bool
+	pathCoverage
+}
+
+func (s *dfsStrategy) Push(c Candidate) {
+	s.add(c.Path)
+	s.stack = append(s.stack, c)
+}
+
+func (s *dfsStrategy) Pop() (Candidate, bool) {
+	n := len(s.stack)
+	if n == 0 {
+		return Candidate{}, false
+	}
+	i := n - 1
+	for j := n - 1; j >= 0; j-- {
+		if s.uncovered(s.stack[j]) {
+			i = j
+			break
+		}
+	}
+	c := s.stack[i]
+	s.stack = append(s.stack[:i], s.stack[i+1:]...)
+	return c, true
+}
+
+// uncovered reports whether the untaken side of the branch of c has not been covered yet.
+// The panicking side of a runtime check is regarded as uncovered.
+func (s *dfsStrategy) uncovered(c Candidate) bool {
+	if c.Index >= len(c.Path.Branches) {
+		return false
+	}
+	b := c.Branch()
+	other := b.Other()
+	if other == nil {
+		_, ok := b.(*solver.BranchInvoke)
+		return !ok
+	}
+	if s.edges {
+		_, ok := s.covered[decision{instr: b.Instr(), to: other}]
+		return !ok
+	}
+	_, ok := s.visited[other]
+	return !ok
+}
+
+// pathCoverage is the set of the blocks visited and the decisions taken by the paths pushed to a strategy.
+type pathCoverage struct {
+	visited map[*ssa.BasicBlock]struct{}
+	covered map[decision]struct{}
+	paths   map[*Path]struct{}
+}
+
+func newPathCoverage() pathCoverage {
+	return pathCoverage{
+		visited: make(map[*ssa.BasicBlock]struct{}),
+		covered: make(map[decision]struct{}),
+		paths:   make(map[*Path]struct{}),
+	}
+}
+
+// add marks the blocks and the decisions in the trace of path as covered.
+// It returns false if path has already been added.
+func (pc pathCoverage) add(path *Path) bool {
+	if _, ok := pc.paths[path]; ok {
+		return false
+	}
+	pc.paths[path] = struct{}{}
+	for i, instr := range path.instrs {
+		pc.visited[instr.Block()] = struct{}{}
+		if _, ok := instr.(*ssa.If); ok && i+1 < len(path.instrs) {
+			pc.covered[decision{instr: instr, to: path.instrs[i+1].Block()}] = struct{}{}
+		}
+	}
+	for _, b := range path.Branches {
+		pc.covered[decisionOf(b)] = struct{}{}
+	}
+	return true
+}
+
+// bfsStrategy is a breadth-first search strategy.
+// It negates the shallowest branch of the oldest path first.
+type bfsStrategy struct {
+	queue []Candidate
+}
+
+func (s *bfsStrategy) Push(c Candidate) {
+	s.queue = append(s.queue, c)
+}
+
+func (s *bfsStrategy) Pop() (Candidate, bool) {
+	if len(s.queue) == 0 {
+		return Candidate{}, false
+	}
+	c := s.queue[0]
+	s.queue = s.queue[1:]
+	return c, true
+}
+
+// generationalStrategy is the generational search of SAGE.
+// Every branch of a path beyond its bound is expanded, and the candidates of the paths
+// that covered more new blocks (or edges if edges is true) are negated first.
+// Ties are broken by the generation and then by the order of pushes.
+type generationalStrategy struct {
+	items generationalItems
+	count int
+	edges bool
+}
+
+type generationalItem struct {
+	c Candidate
+	// score is the number of blocks or edges first covered by the path of c.
+	score int
+	order int
+}
+
+type generationalItems []generationalItem
+
+func (items generationalItems) Len() int { return len(items) }
+
+func (items generationalItems) Less(i, j int) bool {
+	pi, pj := items[i].c.Path, items[j].c.Path
+	if items[i].score != items[j].score {
+		return items[i].score > items[j].score
+	}
+	if pi.Generation != pj.Generation {
+		return pi.Generation < pj.Generation
+	}
+	return items[i].order < items[j].order
+}
+
+func (items generationalItems) Swap(i, j int) { items[i], items[j] = items[j], items[i] }
+
+func (items *generationalItems) Push(x interface{}) {
+	*items = append(*items, x.(generationalItem))
+}
+
+func (items *generationalItems) Pop() interface{} {
+	old := *items
+	n := len(old)
+	item := old[n-1]
+	*items = old[:n-1]
+	return item
+}
+
+func (s *generationalStrategy) Push(c Candidate) {
+	score := c.Path.NewBlocks
+	if s.edges {
+		score = c.Path.NewEdges
+	}
+	heap.Push(&s.items, generationalItem{c: c, score: score, order: s.count})
+	s.count++
+}
+
+func (s *generationalStrategy) Pop() (Candidate, bool) {
+	if s.items.Len() == 0 {
+		return Candidate{}, false
+	}
+	return heap.Pop(&s.items).(generationalItem).c, true
+}
+
+// randomStrategy is a random-path strategy.
+// It chooses a candidate at random, weighted by 2^-d where d is the depth of the branch,
+// which amounts to walking down the execution tree choosing each side with equal probability.
+// Shallow branches are favored so that the search is not trapped in a deep loop.
+type randomStrategy struct {
+	rand       *rand.Rand
+	candidates []Candidate
+}
+
+func (s *randomStrategy) Push(c Candidate) {
+	s.candidates = append(s.candidates, c)
+}
+
+func (s *randomStrategy) Pop() (Candidate, bool) {
+	n := len(s.candidates)
+	if n == 0 {
+		return Candidate{}, false
+	}
+	weights := make([]float64, n)
+	total := 0.0
+	for i, c := range s.candidates {
+		weights[i] = math.Ldexp(1, -c.Index)
+		total += weights[i]
+	}
+	var i int
+	if total == 0 {
+		// All the weights underflow if the branches are extremely deep.
+		i = s.rand.Intn(n)
+	} else {
+		x := s.rand.Float64() * total
+		for ; i < n-1 && x >= weights[i]; i++ {
+			x -= weights[i]
+		}
+	}
+	c := s.candidates[i]
+	s.candidates = append(s.candidates[:i], s.candidates[i+1:]...)
+	return c, true
+}
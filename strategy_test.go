@@ -0,0 +1,217 @@
+package congo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/ajalab/congo/solver"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+func popAll(s Strategy) []Candidate {
+	var cs []Candidate
+	for {
+		c, ok := s.Pop()
+		if !ok {
+			return cs
+		}
+		cs = append(cs, c)
+	}
+}
+
+func TestStrategy(t *testing.T) {
+	p0 := &Path{NewBlocks: 1}
+	p1 := &Path{NewBlocks: 3, Generation: 1}
+	p2 := &Path{NewBlocks: 3, Generation: 2}
+	pushed := []Candidate{{p0, 0}, {p0, 1}, {p1, 2}, {p2, 3}, {p1, 3}}
+
+	tcs := []struct {
+		name string
+		ans  []Candidate
+	}{
+		{"dfs", []Candidate{{p1, 3}, {p2, 3}, {p1, 2}, {p0, 1}, {p0, 0}}},
+		{"bfs", pushed},
+		{"generational", []Candidate{{p1, 2}, {p1, 3}, {p2, 3}, {p0, 0}, {p0, 1}}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewStrategy(tc.name, nil, "block")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range pushed {
+				s.Push(c)
+			}
+			actual := popAll(s)
+			if fmt.Sprint(actual) != fmt.Sprint(tc.ans) {
+				t.Errorf("expected %v, actual %v", tc.ans, actual)
+			}
+		})
+	}
+}
+
+func TestGenerationalStrategyByEdges(t *testing.T) {
+	p0 := &Path{NewBlocks: 2, NewEdges: 1}
+	p1 := &Path{NewBlocks: 1, NewEdges: 2}
+	s, err := NewStrategy("generational", nil, "edge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Push(Candidate{p0, 0})
+	s.Push(Candidate{p1, 0})
+	ans := []Candidate{{p1, 0}, {p0, 0}}
+	actual := popAll(s)
+	if fmt.Sprint(actual) != fmt.Sprint(ans) {
+		t.Errorf("expected %v, actual %v", ans, actual)
+	}
+}
+
+func TestRandomStrategy(t *testing.T) {
+	s, err := NewStrategy("random", nil, "block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Path{}
+	for j := 0; j < 8; j++ {
+		s.Push(Candidate{p, j})
+	}
+	popped := make(map[int]bool)
+	for _, c := range popAll(s) {
+		if popped[c.Index] {
+			t.Errorf("candidate %d is popped twice", c.Index)
+		}
+		popped[c.Index] = true
+	}
+	if len(popped) != 8 {
+		t.Errorf("expected 8 candidates, actual %d", len(popped))
+	}
+}
+
+func TestUnknownStrategy(t *testing.T) {
+	if _, err := NewStrategy("foo", nil, "block"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
+
+const directedSrc = `package p
+
+func f(x int) int {
+	if x > 0 {
+		return 1
+	}
+	if x < -10 {
+		return g(x)
+	}
+	return 0
+}
+
+func g(x int) int {
+	if x < -100 {
+		return 2
+	}
+	return 3
+}
+`
+
+// otherBranch is a branch that only has the untaken side.
+type otherBranch struct {
+	other *ssa.BasicBlock
+}
+
+func (b *otherBranch) Instr() ssa.Instruction { return nil }
+func (b *otherBranch) To() *ssa.BasicBlock    { return nil }
+func (b *otherBranch) Other() *ssa.BasicBlock { return b.other }
+func (b *otherBranch) String() string         { return b.other.String() }
+
+// directedPaths returns the function f of directedSrc and the paths of f(-20) and f(5).
+func directedPaths(t *testing.T) (*ssa.Function, *Path, *Path) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", directedSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{Importer: importer.Default()}, fset,
+		types.NewPackage("p", ""), []*ast.File{file}, ssa.SanityCheckFunctions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, g := pkg.Func("f"), pkg.Func("g")
+	fThen, fDone := f.Blocks[0].Succs[0], f.Blocks[0].Succs[1]
+	fCall, fRet0 := fDone.Succs[0], fDone.Succs[1]
+	gThen, gDone := g.Blocks[0].Succs[0], g.Blocks[0].Succs[1]
+
+	instrs := func(blocks ...*ssa.BasicBlock) []ssa.Instruction {
+		var instrs []ssa.Instruction
+		for _, b := range blocks {
+			instrs = append(instrs, b.Instrs...)
+		}
+		return instrs
+	}
+	// p1 is the path of f(-20) and p2 is that of f(5).
+	p1 := &Path{
+		Branches: []solver.Branch{&otherBranch{fThen}, &otherBranch{fRet0}, &otherBranch{gThen}},
+		instrs:   instrs(f.Blocks[0], fDone, fCall, g.Blocks[0], gDone),
+	}
+	p2 := &Path{
+		Branches: []solver.Branch{&otherBranch{fDone}},
+		instrs:   instrs(f.Blocks[0], fThen),
+	}
+	return f, p1, p2
+}
+
+func TestDFSStrategyPrefersUncovered(t *testing.T) {
+	f, p1, p2 := directedPaths(t)
+	tcs := []struct {
+		metric string
+		ans    []Candidate
+	}{
+		// fDone and fThen have been visited by p1 and p2.
+		{"block", []Candidate{{p1, 2}, {p1, 1}, {p2, 0}, {p1, 0}}},
+		// None of the edges to the untaken sides has been covered.
+		{"edge", []Candidate{{p2, 0}, {p1, 2}, {p1, 1}, {p1, 0}}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.metric, func(t *testing.T) {
+			s, err := NewStrategy("dfs", f, tc.metric)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j := range p1.Branches {
+				s.Push(Candidate{p1, j})
+			}
+			s.Push(Candidate{p2, 0})
+
+			actual := popAll(s)
+			if fmt.Sprint(actual) != fmt.Sprint(tc.ans) {
+				t.Errorf("expected %v, actual %v", tc.ans, actual)
+			}
+		})
+	}
+}
+
+func TestDirectedStrategy(t *testing.T) {
+	f, p1, p2 := directedPaths(t)
+	s, err := NewStrategy("directed", f, "block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := range p1.Branches {
+		s.Push(Candidate{p1, j})
+	}
+	s.Push(Candidate{p2, 0})
+
+	// fRet0 and gThen are not visited, fDone reaches fRet0 in one step,
+	// and no block that is not visited is reachable from fThen.
+	ans := []Candidate{{p1, 2}, {p1, 1}, {p2, 0}, {p1, 0}}
+	actual := popAll(s)
+	if fmt.Sprint(actual) != fmt.Sprint(ans) {
+		t.Errorf("expected %v, actual %v", ans, actual)
+	}
+}
@@ -112,3 +112,19 @@ func BranchSwitch(x int) {
 		fmt.Println("x is neither 0, 1, nor 2")
 	}
 }
+
+// BranchEdge is a test case for checking the edge coverage.
+// The runs with x = 0 and x = 2 cover all the blocks, but not the false edge of x > 1.
+// congo:maxexec 3
+// congo:metric edge
+// congo:cover 1.0
+func BranchEdge(x int32) int32 {
+	y := int32(0)
+	if x > 0 {
+		if x > 1 {
+			y = 2
+		}
+		y++
+	}
+	return y
+}
@@ -1,6 +1,9 @@
 package testdata
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 func plus(a, b int) int {
 	return a + b
@@ -19,6 +22,27 @@ func UsePlus(a, b, c int) {
 	}
 }
 
+func divmod(a, b int) (int, int, error) {
+	if b == 0 {
+		return 0, 0, errors.New("division by zero")
+	}
+	return a / b, a % b, nil
+}
+
+// UseDivmod is a test case for checking function calls with multiple return values.
+// congo:maxexec 4
+// congo:cover 1.0
+func UseDivmod(a, b int) {
+	q, r, err := divmod(a, b)
+	if err != nil {
+		fmt.Println("b == 0")
+		return
+	}
+	if q == 3 && r == 1 {
+		fmt.Println("a / b == 3 and a % b == 1")
+	}
+}
+
 /*
 func factor(n int) int {
 	if n > 1 {
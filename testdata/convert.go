@@ -0,0 +1,55 @@
+package testdata
+
+import "fmt"
+
+// ConvertIntWidth is a test case to check truncation and sign extension of integers.
+// congo:maxexec 3
+// congo:cover 1.0
+func ConvertIntWidth(x int) {
+	if int8(x) == -1 {
+		fmt.Println("the lowest byte of x is 0xff")
+	} else {
+		fmt.Println("the lowest byte of x is not 0xff")
+	}
+}
+
+// ConvertIntFloat is a test case to check conversions between integers and floating-point numbers.
+// congo:maxexec 3
+// congo:cover 1.0
+func ConvertIntFloat(x float64) {
+	if int(x) == 3 {
+		fmt.Println("int(x) is 3")
+	} else {
+		fmt.Println("int(x) is not 3")
+	}
+}
+
+// ConvertRune is a test case to check conversions from runes to strings.
+// congo:maxexec 3
+// congo:cover 1.0
+func ConvertRune(r rune) {
+	if string(r) == "é" {
+		fmt.Println("r is é")
+	} else {
+		fmt.Println("r is not é")
+	}
+}
+
+// ConvertBytes is a test case to check conversions from strings to byte slices.
+// congo:maxexec 3
+// congo:cover 1.0
+func ConvertBytes(s string) {
+	b := []byte(s)
+	if len(b) > 1 && b[1] == 'o' {
+		fmt.Println("the second byte is 'o'")
+	}
+}
+
+// ConvertRunes is a test case to check conversions from rune slices to strings.
+// congo:maxexec 3
+// congo:cover 1.0
+func ConvertRunes(rs []rune) {
+	if string(rs) == "go" {
+		fmt.Println("rs is go")
+	}
+}
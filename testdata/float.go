@@ -0,0 +1,35 @@
+package testdata
+
+import "fmt"
+
+// FloatCompare is a test case to check comparison of floating-point numbers.
+// congo:maxexec 3
+// congo:cover 1.0
+func FloatCompare(x float64) {
+	if x*2 > 3.5 {
+		fmt.Println("x * 2 > 3.5")
+	} else {
+		fmt.Println("x * 2 <= 3.5")
+	}
+}
+
+// FloatIsNaN is a test case to check NaN handling.
+// congo:maxexec 2
+// congo:cover 1.0
+func FloatIsNaN(x float32) bool {
+	if x != x {
+		return true
+	}
+	return false
+}
+
+// FloatDiv is a test case to check division of floating-point numbers.
+// congo:maxexec 3
+// congo:cover 1.0
+func FloatDiv(x, y float64) {
+	if x/y == 0.25 {
+		fmt.Println("x / y is 0.25")
+	} else {
+		fmt.Println("x / y is not 0.25")
+	}
+}
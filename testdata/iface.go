@@ -0,0 +1,60 @@
+package testdata
+
+import "fmt"
+
+// Shape is an interface to check interface-typed parameters.
+type Shape interface {
+	Area() int
+}
+
+// Rect is a rectangle implementing Shape.
+type Rect struct {
+	W, H int
+}
+
+// Area returns the area of the rectangle.
+func (r Rect) Area() int {
+	return r.W * r.H
+}
+
+// Square is a square implementing Shape with a pointer receiver.
+type Square struct {
+	L int
+}
+
+// Area returns the area of the square.
+func (s *Square) Area() int {
+	return s.L * s.L
+}
+
+// DescribeShape is a test case to check type switches on an interface parameter.
+// congo:maxexec 6
+// congo:cover 1.0
+func DescribeShape(s Shape) {
+	switch s := s.(type) {
+	case Rect:
+		if s.W == s.H {
+			fmt.Println("a square-shaped rectangle")
+		}
+	case *Square:
+		if s.L > 10 {
+			fmt.Println("a large square")
+		}
+	}
+}
+
+// LargeShape is a test case to check method calls through an interface.
+// congo:maxexec 3
+// congo:cover 1.0
+func LargeShape(s Shape) {
+	if s.Area() > 100 {
+		fmt.Println("a large shape")
+	}
+}
+
+// AssertRect is a test case to check type assertions that may panic.
+// congo:maxexec 3
+// congo:cover 1.0
+func AssertRect(s Shape) int {
+	return s.(Rect).W
+}
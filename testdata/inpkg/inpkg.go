@@ -0,0 +1,16 @@
+// Package inpkg is a test subject of the in-package mode, whose target function is unexported.
+package inpkg
+
+type counter struct {
+	n int
+}
+
+// atLeast returns x if it is at least the count of c, and the count otherwise.
+// congo:maxexec 10
+// congo:cover 1.0
+func atLeast(c counter, x int) int {
+	if x < c.n {
+		return c.n
+	}
+	return x
+}
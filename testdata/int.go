@@ -197,3 +197,75 @@ func ShiftRight2(n uint8) bool {
 	}
 	return false
 }
+
+// DivZero is a test case to check integer division and remainder that may panic by division by zero.
+// congo:maxexec 5
+// congo:cover 1.0
+// congo:metric edge
+func DivZero(a, b int) int {
+	if a > 100 {
+		return a % b
+	}
+	return a / (b - 1)
+}
+
+// TotalPrice is a test case to check the detection of integer overflows.
+// congo:maxexec 3
+// congo:cover 1.0
+// congo:overflow
+// congo:overflowtests
+func TotalPrice(price uint32, quantity uint16) uint32 {
+	if quantity == 0 {
+		return 0
+	}
+	return price * uint32(quantity)
+}
+
+// AddInt8 is a test case to check the detection of overflows of signed addition.
+// congo:maxexec 1
+// congo:overflow
+func AddInt8(x, y int8) int8 {
+	return x + y
+}
+
+// SubInt16 is a test case to check the detection of overflows of signed subtraction.
+// congo:maxexec 1
+// congo:overflow
+func SubInt16(x, y int16) int16 {
+	return x - y
+}
+
+// MulInt32 is a test case to check the detection of overflows of signed multiplication.
+// congo:maxexec 1
+// congo:overflow
+func MulInt32(x, y int32) int32 {
+	return x * y
+}
+
+// ShlInt64 is a test case to check the detection of overflows of signed left shift.
+// congo:maxexec 1
+// congo:overflow
+func ShlInt64(x int64, n uint8) int64 {
+	return x << n
+}
+
+// NegInt8 is a test case to check the detection of overflows of signed negation.
+// congo:maxexec 1
+// congo:overflow
+func NegInt8(x int8) int8 {
+	return -x
+}
+
+// NarrowInt64 is a test case to check the detection of overflows of narrowing conversions.
+// congo:maxexec 1
+// congo:overflow
+func NarrowInt64(x int64) int8 {
+	return int8(x)
+}
+
+// Int8ToUint16 is a test case to check the detection of overflows of conversions from signed to unsigned integers.
+// congo:maxexec 1
+// congo:overflow
+func Int8ToUint16(x int8) uint16 {
+	return uint16(x)
+}
@@ -3,6 +3,16 @@ package load
 
 // AnnotatedBar ...
 // congo:maxexec 50
+// congo:strategy bfs
+// congo:querytimeout 500ms
+// congo:timeout 1m
+// congo:maxsteps 100000
+// congo:metric edge
+// congo:solver smtlib
+// congo:solvercmd cvc5 --lang smt2 --incremental
+// congo:readable
+// congo:overflow
+// congo:overflowtests
 func AnnotatedBar() {
 
 }
@@ -12,3 +12,19 @@ func AnnotatedFoo() {
 func NonAnnotatedFoo() {
 
 }
+
+// Foo ...
+type Foo struct{}
+
+// AnnotatedMethod ...
+// congo:maxexec 20
+// congo:loopbound 3
+// congo:summarizeloops
+func (f *Foo) AnnotatedMethod() {
+
+}
+
+// NonAnnotatedMethod ...
+func (f Foo) NonAnnotatedMethod() {
+
+}
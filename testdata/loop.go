@@ -18,3 +18,64 @@ func LoopInt(x int) {
 		fmt.Println("sum of 1 .. x is greater than 50")
 	}
 }
+
+// LoopSummary is a test case for checking the summary of counting loops.
+// The then block of the last if is covered by solving the trip count of the loop,
+// which would take 50 runs if the loop were negated one iteration at a time.
+// congo:maxexec 5
+// congo:summarizeloops
+// congo:cover 1.0
+func LoopSummary(n int) int {
+	i := 0
+	for i < n {
+		i += 2
+	}
+	if i == 100 {
+		return 1
+	}
+	return 0
+}
+
+// LoopUntilZero is a test case for checking the budget of the interpreter.
+// It does not terminate if x is negative.
+// congo:maxexec 5
+// congo:maxsteps 100000
+// congo:cover 1.0
+func LoopUntilZero(x int) int {
+	n := 0
+	for x != 0 {
+		x--
+		n++
+	}
+	return n
+}
+
+// CountPositive is a test case for checking the loop bound.
+// All the blocks are covered in the first two iterations.
+// congo:maxexec 10
+// congo:loopbound 2
+// congo:summarizeloops
+// congo:cover 1.0
+func CountPositive(xs []int) int {
+	n := 0
+	for _, x := range xs {
+		if x > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// LoopForever is a test case for checking a run whose trace is cut off by the budget in the middle of a loop.
+// The body of the loop is covered only by the runs that do not terminate.
+// congo:maxexec 3
+// congo:maxtrace 1000
+// congo:cover 1.0
+func LoopForever(x int) int {
+	n := 0
+	for x != 0 {
+		x |= 1
+		n++
+	}
+	return n
+}
@@ -0,0 +1,40 @@
+package testdata
+
+import "fmt"
+
+// MapLookup is a test case to check lookup of a map.
+// congo:maxexec 3
+// congo:cover 1.0
+func MapLookup(m map[string]int) {
+	if m["foo"] == 3 {
+		fmt.Println(`m["foo"] is 3`)
+	} else {
+		fmt.Println(`m["foo"] is not 3`)
+	}
+}
+
+// MapLookupCommaOk is a test case to check lookup of a map with comma-ok.
+// congo:maxexec 4
+// congo:cover 1.0
+func MapLookupCommaOk(m map[int]bool, k int) int {
+	v, ok := m[k]
+	if !ok {
+		return 0
+	}
+	if v {
+		return 1
+	}
+	return 2
+}
+
+// MapUpdate is a test case to check assignment to a map and its length.
+// congo:maxexec 4
+// congo:cover 1.0
+func MapUpdate(m map[uint8]uint8, k uint8) {
+	m[k] = 1
+	if len(m) == 2 {
+		fmt.Println("len(m) is 2")
+	} else {
+		fmt.Println("len(m) is not 2")
+	}
+}
@@ -0,0 +1,29 @@
+package testdata
+
+import "fmt"
+
+// Counter counts events up to the limit.
+type Counter struct {
+	N, Limit int
+}
+
+// Full is a test case to check methods with a value receiver.
+// congo:maxexec 3
+// congo:cover 1.0
+func (c Counter) Full() bool {
+	if c.N >= c.Limit {
+		return true
+	}
+	return false
+}
+
+// Add is a test case to check methods with a pointer receiver.
+// congo:maxexec 4
+// congo:cover 1.0
+func (c *Counter) Add(d int) {
+	if c.N+d > c.Limit {
+		fmt.Println("the counter overflows")
+		return
+	}
+	c.N += d
+}
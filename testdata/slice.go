@@ -0,0 +1,74 @@
+package testdata
+
+import "fmt"
+
+// SliceIndex is a test case to check indexing and bounds checking of a slice.
+// congo:maxexec 4
+// congo:cover 1.0
+func SliceIndex(a []int) {
+	if a[1] == 5 {
+		fmt.Println("a[1] is 5")
+	} else {
+		fmt.Println("a[1] is not 5")
+	}
+}
+
+// SliceLen is a test case to check the length of a slice.
+// congo:maxexec 3
+// congo:cover 1.0
+func SliceLen(a []string) bool {
+	if len(a) == 3 {
+		return a[2] == "foo"
+	}
+	return false
+}
+
+// SliceSum is a test case to check a loop over a slice.
+// congo:maxexec 10
+// congo:cover 1.0
+func SliceSum(a []int) {
+	sum := 0
+	for _, x := range a {
+		sum += x
+	}
+	if sum == 10 {
+		fmt.Println("sum is 10")
+	} else {
+		fmt.Println("sum is not 10")
+	}
+}
+
+// SliceSlice is a test case to check slicing of a slice.
+// congo:maxexec 6
+// congo:cover 1.0
+func SliceSlice(a []uint8) {
+	b := a[1:3]
+	if b[0] == b[1] {
+		fmt.Println("a[1] == a[2]")
+	} else {
+		fmt.Println("a[1] != a[2]")
+	}
+}
+
+// ArrayMax is a test case to check indexing of an array.
+// congo:maxexec 6
+// congo:cover 1.0
+func ArrayMax(a [3]int) int {
+	m := a[0]
+	for i := 1; i < len(a); i++ {
+		if a[i] > m {
+			m = a[i]
+		}
+	}
+	return m
+}
+
+// LongArray is a test case to check an array longer than the bound of the length of symbolic slices.
+// congo:maxexec 4
+// congo:cover 1.0
+func LongArray(a [32]byte) bool {
+	if a[20] == 'x' && a[31] == 'y' {
+		return true
+	}
+	return false
+}
@@ -1,6 +1,9 @@
 package testdata
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // IsABC is a function that checks if s is equal to "ABC".
 // congo:maxexec 2
@@ -35,6 +38,72 @@ func IsLength3(s string) {
 	}
 }
 
+// HasXYAt1 checks whether s[1:3] is "xy". It panics if s is too short.
+// congo:maxexec 3
+// congo:cover 1.0
+func HasXYAt1(s string) {
+	if s[1:3] == "xy" {
+		fmt.Println("s[1:3] is xy")
+	} else {
+		fmt.Println("s[1:3] is not xy")
+	}
+}
+
+// IsSecondByteA checks whether the second byte of s is 'a'. It panics if len(s) < 2.
+// congo:maxexec 3
+// congo:cover 1.0
+func IsSecondByteA(s string) {
+	if s[1] == 'a' {
+		fmt.Println("s[1] is a")
+	} else {
+		fmt.Println("s[1] is not a")
+	}
+}
+
+// Compare compares two strings lexicographically.
+// congo:maxexec 3
+// congo:cover 1.0
+func Compare(s1, s2 string) {
+	if s1 < s2 {
+		fmt.Println("s1 < s2")
+	} else if s1 <= s2 {
+		fmt.Println("s1 == s2")
+	} else {
+		fmt.Println("s1 > s2")
+	}
+}
+
+// StartsWithE checks whether the first rune of s is 'é'.
+// congo:maxexec 3
+// congo:cover 1.0
+func StartsWithE(s string) {
+	for _, r := range s {
+		if r == 'é' {
+			fmt.Println("s starts with é")
+		}
+		return
+	}
+	fmt.Println("s is empty")
+}
+
+// IsKeyValue checks whether s is of the form "key=value" with a "k" prefixed key.
+// congo:maxexec 5
+// congo:cover 1.0
+func IsKeyValue(s string) {
+	if !strings.Contains(s, "=") {
+		fmt.Println("s has no =")
+		return
+	}
+	kv := strings.Split(s, "=")
+	if len(kv) != 2 {
+		fmt.Println("s has more than one =")
+		return
+	}
+	if strings.HasPrefix(kv[0], "k") {
+		fmt.Println("s is key=value")
+	}
+}
+
 /*
 // IsABCIfConcatenatedNonNull is a function that checks if the concatenated non-null string s1 + s2 is equal to "ABC".
 func IsABCIfConcatenatedNonNull(s1, s2 string) {
@@ -0,0 +1,58 @@
+package testdata
+
+import "fmt"
+
+// Point is a point on a two-dimensional plane.
+type Point struct {
+	X, Y int
+}
+
+// Segment is a pair of points.
+type Segment struct {
+	From, To Point
+}
+
+// StructField is a test case to check field access of a struct value.
+// congo:maxexec 3
+// congo:cover 1.0
+func StructField(p Point) {
+	if p.X > 0 && p.Y == p.X*2 {
+		fmt.Println("p is on y = 2x")
+	} else {
+		fmt.Println("p is not on y = 2x")
+	}
+}
+
+// StructNestedField is a test case to check field access of a nested struct value.
+// congo:maxexec 3
+// congo:cover 1.0
+func StructNestedField(s Segment) bool {
+	if s.From.X == s.To.X {
+		return s.From.Y != s.To.Y
+	}
+	return true
+}
+
+// StructPointerField is a test case to check field access through a pointer to a struct.
+// congo:maxexec 3
+// congo:cover 1.0
+func StructPointerField(p *Point) {
+	if p.X == 10 {
+		fmt.Println("p.X is 10")
+	} else {
+		fmt.Println("p.X is not 10")
+	}
+}
+
+// StructFieldStore is a test case to check storing a value to a field.
+// congo:maxexec 4
+// congo:cover 1.0
+func StructFieldStore(p *Point) {
+	if p == nil {
+		return
+	}
+	p.X = p.Y + 1
+	if p.X == 5 {
+		fmt.Println("p.X is 5")
+	}
+}
@@ -6,9 +6,14 @@ import (
 	"go/token"
 	"go/types"
 	"log"
+	"math"
+	"reflect"
+	"sort"
 	"strconv"
 	"unsafe"
 
+	"github.com/ajalab/congo/interp"
+
 	"golang.org/x/tools/go/ssa"
 )
 
@@ -69,7 +74,7 @@ func zero(ty types.Type) interface{} {
 	case *types.Named:
 		return zero(t.Underlying())
 	case *types.Interface:
-		panic("unimplemented")
+		return nil
 	case *types.Slice:
 		return []interface{}(nil)
 	case *types.Struct:
@@ -90,7 +95,8 @@ func zero(ty types.Type) interface{} {
 	case *types.Chan:
 		return chan interface{}(nil)
 	case *types.Map:
-		return map[interface{}][]interface{}(nil)
+		// The solver assumes that symbolic maps are not nil.
+		return map[interface{}]interface{}{}
 	case *types.Signature:
 		return (*ssa.Function)(nil)
 	}
@@ -103,14 +109,51 @@ func type2ASTExpr(ty types.Type) ast.Expr {
 	case *types.Basic:
 		return ast.NewIdent(ty.Name())
 	case *types.Named:
+		if ty.Obj().Pkg() == nil {
+			// Predeclared types such as error
+			return ast.NewIdent(ty.Obj().Name())
+		}
 		return &ast.SelectorExpr{
 			X:   ast.NewIdent(ty.Obj().Pkg().Name()),
-			Sel: ast.NewIdent(ty.Obj().Id()),
+			Sel: ast.NewIdent(ty.Obj().Name()),
 		}
 	case *types.Pointer:
 		return &ast.StarExpr{
 			X: type2ASTExpr(ty.Elem()),
 		}
+	case *types.Struct:
+		n := ty.NumFields()
+		fields := make([]*ast.Field, n)
+		for i := 0; i < n; i++ {
+			f := ty.Field(i)
+			fields[i] = &ast.Field{Type: type2ASTExpr(f.Type())}
+			if !f.Anonymous() {
+				fields[i].Names = []*ast.Ident{ast.NewIdent(f.Name())}
+			}
+		}
+		return &ast.StructType{Fields: &ast.FieldList{List: fields}}
+	case *types.Slice:
+		return &ast.ArrayType{
+			Elt: type2ASTExpr(ty.Elem()),
+		}
+	case *types.Array:
+		return &ast.ArrayType{
+			Len: &ast.BasicLit{
+				Kind:  token.INT,
+				Value: strconv.FormatInt(ty.Len(), 10),
+			},
+			Elt: type2ASTExpr(ty.Elem()),
+		}
+	case *types.Map:
+		return &ast.MapType{
+			Key:   type2ASTExpr(ty.Key()),
+			Value: type2ASTExpr(ty.Elem()),
+		}
+	case *types.Interface:
+		if ty.NumMethods() == 0 {
+			return &ast.InterfaceType{Methods: &ast.FieldList{}}
+		}
+		panic("unimplemented")
 	default:
 		panic("unimplemented")
 	}
@@ -132,6 +175,8 @@ func value2ASTExpr(v interface{}, ty types.Type) ast.Expr {
 				Kind:  token.INT,
 				Value: fmt.Sprintf("%v", v),
 			}
+		case info&types.IsFloat > 0:
+			return floatValue2ASTExpr(v, ty)
 		case info&types.IsString > 0:
 			return &ast.BasicLit{
 				Kind:  token.STRING,
@@ -141,51 +186,201 @@ func value2ASTExpr(v interface{}, ty types.Type) ast.Expr {
 			panic("unimplemented")
 		}
 	case *types.Pointer:
-		p := v.(*interface{})
-		if p == nil {
+		// v is either *interface{} or *interp.value.
+		p := reflect.ValueOf(v)
+		if p.IsNil() {
 			return ast.NewIdent("nil")
 		}
+		elem := p.Elem().Interface()
 		if basicTy, ok := ty.Elem().(*types.Basic); ok {
 			return &ast.CallExpr{
 				Fun: ast.NewIdent(basicTy.Name() + "ptr"),
 				Args: []ast.Expr{
-					value2ASTExpr(*p, basicTy),
+					value2ASTExpr(elem, basicTy),
 				},
 			}
 		}
 
-		namedTy, ok := ty.Elem().(*types.Named)
-		if !ok {
-			log.Fatalf("pointer of unnamed and non-basic type is not supported")
-			panic("unimplemented")
-		}
-		typeName := namedTy.Obj()
-		name := typeName.Name()
-		pkgName := typeName.Pkg().Name()
-		elem := ty.Elem().Underlying()
-		switch ty := elem.(type) {
-		case *types.Struct:
-			n := ty.NumFields()
-			elts := make([]ast.Expr, 0, n)
-			for i := 0; i < n; i++ {
-				e := ty.Field(i)
-				elts = append(elts, &ast.KeyValueExpr{
-					Key:   ast.NewIdent(e.Name()),
-					Value: value2ASTExpr(((*p).([]interface{}))[i], e.Type()),
-				})
-			}
+		if _, ok := ty.Elem().Underlying().(*types.Struct); ok {
 			return &ast.UnaryExpr{
 				Op: token.AND,
-				X: &ast.CompositeLit{
-					Type: &ast.SelectorExpr{
-						X:   ast.NewIdent(pkgName),
-						Sel: ast.NewIdent(name),
-					},
-					Elts: elts,
-				},
+				X:  value2ASTExpr(elem, ty.Elem()),
 			}
 		}
+		log.Fatalf("pointer of non-basic and non-struct type is not supported")
 		panic("unimplemented")
+	case *types.Named:
+		switch underlying := ty.Underlying().(type) {
+		case *types.Basic:
+			if underlying.Info()&types.IsFloat > 0 {
+				return floatValue2ASTExpr(v, ty)
+			}
+		case *types.Struct:
+			return structValue2ASTExpr(v, underlying, type2ASTExpr(ty))
+		case *types.Slice:
+			return seqValue2ASTExpr(v, underlying.Elem(), type2ASTExpr(ty))
+		case *types.Array:
+			return seqValue2ASTExpr(v, underlying.Elem(), type2ASTExpr(ty))
+		case *types.Map:
+			return mapValue2ASTExpr(v, underlying, type2ASTExpr(ty))
+		case *types.Interface:
+			return ifaceValue2ASTExpr(v)
+		}
+		return value2ASTExpr(v, ty.Underlying())
+	case *types.Struct:
+		return structValue2ASTExpr(v, ty, type2ASTExpr(ty))
+	case *types.Slice:
+		return seqValue2ASTExpr(v, ty.Elem(), type2ASTExpr(ty))
+	case *types.Array:
+		return seqValue2ASTExpr(v, ty.Elem(), type2ASTExpr(ty))
+	case *types.Map:
+		return mapValue2ASTExpr(v, ty, type2ASTExpr(ty))
+	case *types.Interface:
+		return ifaceValue2ASTExpr(v)
 	}
 	panic("unimplemented")
 }
+
+// ifaceValue2ASTExpr returns an expression of the interface value v,
+// which is either nil or interp.SymbolicValue holding the dynamic type and value.
+// Values of named basic types are converted explicitly so that they have the dynamic type.
+func ifaceValue2ASTExpr(v interface{}) ast.Expr {
+	if v == nil {
+		return ast.NewIdent("nil")
+	}
+	d, ok := v.(interp.SymbolicValue)
+	if !ok {
+		panic("unimplemented")
+	}
+	expr := value2ASTExpr(d.Value, d.Type)
+	if named, ok := d.Type.(*types.Named); ok {
+		if basicTy, ok := named.Underlying().(*types.Basic); ok && basicTy.Info()&types.IsFloat == 0 {
+			// Floating-point values are converted by floatValue2ASTExpr.
+			return &ast.CallExpr{
+				Fun:  type2ASTExpr(named),
+				Args: []ast.Expr{expr},
+			}
+		}
+	}
+	return expr
+}
+
+// mapValue2ASTExpr returns a composite literal of the map value v.
+// v is either a Go map (e.g., map[interface{}]interface{}) or an interp value, so we use reflection to extract entries.
+// Entries are sorted by their keys to make the output deterministic.
+func mapValue2ASTExpr(v interface{}, ty *types.Map, tyExpr ast.Expr) ast.Expr {
+	entries := reflect.ValueOf(v)
+	if entries.Kind() != reflect.Map {
+		panic("unimplemented")
+	}
+	if entries.IsNil() {
+		return ast.NewIdent("nil")
+	}
+	keys := entries.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	elts := make([]ast.Expr, len(keys))
+	for i, k := range keys {
+		elts[i] = &ast.KeyValueExpr{
+			Key:   value2ASTExpr(k.Interface(), ty.Key()),
+			Value: value2ASTExpr(entries.MapIndex(k).Interface(), ty.Elem()),
+		}
+	}
+	return &ast.CompositeLit{
+		Type: tyExpr,
+		Elts: elts,
+	}
+}
+
+// floatValue2ASTExpr returns an expression of the floating-point value v of type ty.
+// NaN, infinities and negative zero are not constants in Go,
+// so they are expressed by function calls in the math package converted to ty if needed.
+func floatValue2ASTExpr(v interface{}, ty types.Type) ast.Expr {
+	var f float64
+	bitSize := 64
+	switch v := v.(type) {
+	case float32:
+		f = float64(v)
+		bitSize = 32
+	case float64:
+		f = v
+	default:
+		panic("unimplemented")
+	}
+
+	var expr ast.Expr
+	switch {
+	case math.IsNaN(f):
+		expr = mathCallExpr("NaN")
+	case math.IsInf(f, 1):
+		expr = mathCallExpr("Inf", &ast.BasicLit{Kind: token.INT, Value: "1"})
+	case math.IsInf(f, -1):
+		expr = mathCallExpr("Inf", &ast.BasicLit{Kind: token.INT, Value: "-1"})
+	case f == 0 && math.Signbit(f):
+		expr = mathCallExpr("Copysign",
+			&ast.BasicLit{Kind: token.INT, Value: "0"},
+			&ast.BasicLit{Kind: token.INT, Value: "-1"},
+		)
+	default:
+		return &ast.BasicLit{
+			Kind:  token.FLOAT,
+			Value: strconv.FormatFloat(f, 'g', -1, bitSize),
+		}
+	}
+	if basicTy, ok := ty.(*types.Basic); ok && basicTy.Kind() == types.Float64 {
+		return expr
+	}
+	return &ast.CallExpr{
+		Fun:  type2ASTExpr(ty),
+		Args: []ast.Expr{expr},
+	}
+}
+
+func mathCallExpr(name string, args ...ast.Expr) ast.Expr {
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   ast.NewIdent("math"),
+			Sel: ast.NewIdent(name),
+		},
+		Args: args,
+	}
+}
+
+// seqValue2ASTExpr returns a composite literal of the slice or array value v.
+// v is either a Go slice (e.g., []interface{}) or an interp value, so we use reflection to extract elements.
+func seqValue2ASTExpr(v interface{}, elemTy types.Type, tyExpr ast.Expr) ast.Expr {
+	elems := reflect.ValueOf(v)
+	if elems.Kind() == reflect.Slice && elems.IsNil() {
+		return ast.NewIdent("nil")
+	}
+	n := elems.Len()
+	elts := make([]ast.Expr, n)
+	for i := 0; i < n; i++ {
+		elts[i] = value2ASTExpr(elems.Index(i).Interface(), elemTy)
+	}
+	return &ast.CompositeLit{
+		Type: tyExpr,
+		Elts: elts,
+	}
+}
+
+// structValue2ASTExpr returns a composite literal of the struct value v.
+// v is a slice of field values (e.g., []interface{} or interp.structure),
+// so we use reflection to extract them.
+func structValue2ASTExpr(v interface{}, ty *types.Struct, tyExpr ast.Expr) ast.Expr {
+	fields := reflect.ValueOf(v)
+	n := ty.NumFields()
+	elts := make([]ast.Expr, 0, n)
+	for i := 0; i < n; i++ {
+		e := ty.Field(i)
+		elts = append(elts, &ast.KeyValueExpr{
+			Key:   ast.NewIdent(e.Name()),
+			Value: value2ASTExpr(fields.Index(i).Interface(), e.Type()),
+		})
+	}
+	return &ast.CompositeLit{
+		Type: tyExpr,
+		Elts: elts,
+	}
+}